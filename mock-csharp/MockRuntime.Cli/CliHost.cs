@@ -29,8 +29,16 @@ public static class CliHost
                 switch (req.Command)
                 {
                     case "initialize":
-                        Respond(output, Protocol.Ok(req.Id, new { capabilities = new { } }));
+                    {
+                        var version = GetArg<string>(req.Args, "protocolVersion") ?? Protocol.Versions[0];
+                        if (!Protocol.Versions.Contains(version))
+                        {
+                            Respond(output, Protocol.Fail(req.Id, $"unsupported protocol version {version}; supported versions: {string.Join(", ", Protocol.Versions)}"));
+                            break;
+                        }
+                        Respond(output, Protocol.Ok(req.Id, new { protocolVersion = version, supportedVersions = Protocol.Versions, capabilities = new { } }));
                         break;
+                    }
 
                     case "attach":
                     {
@@ -49,6 +49,8 @@ public record Breakpoint(int Id, int Line, bool Verified);
 
 public static class Protocol
 {
+    public static readonly string[] Versions = ["1.0"];
+
     public static Response Ok(int id, object? body = null) => new("response", id, true, body, null);
     public static Response Fail(int id, string message) => new("response", id, false, null, message);
 }
\ No newline at end of file
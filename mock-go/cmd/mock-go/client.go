@@ -0,0 +1,360 @@
+package main
+
+import (
+    "bufio"
+    "encoding/json"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+
+    "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/client"
+    p "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/protocol"
+    "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/server"
+)
+
+const clientHelp = `Lines are zero-based, as in the protocol.
+  launch <program>        launch and stop on entry (run <program> does not stop)
+  break [file:]<line>...  add breakpoints (file defaults to the program)
+  delete [[file:]<line>]  remove a breakpoint, or all of them
+  continue, c             run to the next stop
+  next, n / step, s / out step over, into, out of
+  pause                   stop a running program
+  stack, bt               show the call stack
+  vars [ref]              show the variables of each scope, or of a structured variable
+  eval, p <expression>    evaluate an expression
+  set <expression> <value> assign a variable or field
+  input <text>            send a line of program input
+  send <command> [json]   send any request and print its response body
+  help, quit`
+
+// runClient implements `mock-go client [flags] [program]`: a REPL driving a
+// runtime over stdio or TCP that prints events as they arrive.
+func runClient(args []string) {
+    fs := flag.NewFlagSet("client", flag.ExitOnError)
+    var (
+        connect = fs.String("connect", "", "host:port of a runtime serving TCP, e.g. mock-go --server")
+        runtime = fs.String("runtime", "", "command line of a runtime to start over stdio (default: mock-go in-process)")
+        verbose = fs.Bool("v", false, "print every event, not only stops, output and breakpoint changes")
+    )
+    fs.Usage = func() {
+        fmt.Fprintln(fs.Output(), "usage: mock-go client [flags] [program]")
+        fs.PrintDefaults()
+        fmt.Fprintln(fs.Output(), "\nCommands:\n"+clientHelp)
+    }
+    fs.Parse(args)
+
+    r := &repl{out: os.Stdout, verbose: *verbose, breaks: map[string][]int{}}
+    var (
+        c   *client.Client
+        err error
+    )
+    switch {
+    case *connect != "":
+        c, err = client.Dial(*connect, r.event)
+    case *runtime != "":
+        fields := strings.Fields(*runtime)
+        c, err = client.Exec(r.event, os.Stderr, fields[0], fields[1:]...)
+    default:
+        inR, inW := io.Pipe()
+        outR, outW := io.Pipe()
+        go func() { server.New(server.Options{}).ServeConn(inR, outW); outW.Close() }()
+        c = client.New(outR, inW, r.event)
+    }
+    if err != nil { fatalf("client: %v", err) }
+    r.c = c
+    defer c.Close()
+
+    quit := make(chan struct{})
+    go func() {
+        select {
+        case <-c.Done():
+            r.printf("runtime disconnected\n")
+            os.Exit(0)
+        case <-quit:
+        }
+    }()
+    if err := c.Call("initialize", p.InitializeArgs{ProtocolVersion: p.CurrentVersion}, nil); err != nil { r.printf("%v\n", err) }
+    if fs.NArg() > 0 { r.exec("launch " + fs.Arg(0)) }
+
+    in := bufio.NewScanner(os.Stdin)
+    for {
+        r.prompt()
+        if !in.Scan() { break }
+        if !r.exec(in.Text()) { break }
+    }
+    close(quit)
+    _ = c.Call("disconnect", nil, nil)
+}
+
+// repl is the client's state: the program launched and the breakpoints
+// set per file, since setBreakpoints replaces a file's breakpoints.
+type repl struct {
+    c       *client.Client
+    out     io.Writer
+    verbose bool
+
+    mu        sync.Mutex // guards out and prompting
+    prompting bool
+
+    program string
+    breaks  map[string][]int
+}
+
+func (r *repl) printf(format string, args ...any) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    fmt.Fprintf(r.out, format, args...)
+}
+
+func (r *repl) prompt() {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.prompting = true
+    fmt.Fprint(r.out, "(mock) ")
+}
+
+// event prints an event; one arriving at the prompt goes on its own line
+// and the prompt is shown again.
+func (r *repl) event(e client.Event) {
+    text := r.describe(e)
+    if text == "" { return }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if r.prompting { fmt.Fprint(r.out, "\n") }
+    fmt.Fprintln(r.out, text)
+    if r.prompting { fmt.Fprint(r.out, "(mock) ") }
+}
+
+// describe renders an event, or returns "" for one not worth showing.
+func (r *repl) describe(e client.Event) string {
+    var b map[string]any
+    _ = json.Unmarshal(e.Body, &b)
+    switch e.Name {
+    case "stopped":
+        s := fmt.Sprintf("stopped (%v)", b["reason"])
+        if line, ok := b["line"].(float64); ok { s += fmt.Sprintf(" at line %d", int(line)) }
+        if ex, ok := b["exception"].(string); ok { s += ": " + ex }
+        return s
+    case "output":
+        text, _ := b["text"].(string)
+        return strings.TrimSuffix(text, "\n")
+    case "terminated":
+        return "terminated"
+    case "breakpoint":
+        if b["reason"] == "new" && !r.verbose { return "" }
+        var bp p.Breakpoint
+        raw, _ := json.Marshal(b["breakpoint"])
+        _ = json.Unmarshal(raw, &bp)
+        return fmt.Sprintf("breakpoint %d %v: %s", bp.ID, b["reason"], formatBreakpoint(bp))
+    case "breakpointValidated":
+        return fmt.Sprintf("breakpoint %v verified: %v", b["id"], b["verified"])
+    }
+    if !r.verbose { return "" }
+    return fmt.Sprintf("event %s %s", e.Name, e.Body)
+}
+
+// exec runs one command line and reports whether to go on.
+func (r *repl) exec(line string) bool {
+    r.mu.Lock()
+    r.prompting = false
+    r.mu.Unlock()
+    cmd, rest, _ := strings.Cut(strings.TrimSpace(line), " ")
+    rest = strings.TrimSpace(rest)
+    var err error
+    switch cmd {
+    case "": return true
+    case "quit", "q", "exit": return false
+    case "help", "h", "?": r.printf("%s\n", clientHelp)
+    case "launch", "run": err = r.launch(rest, cmd == "launch")
+    case "break", "b": err = r.setBreaks(rest, true)
+    case "delete", "d": err = r.setBreaks(rest, false)
+    case "continue", "c": err = r.c.Call("continue", nil, nil)
+    case "next", "n": err = r.c.Call("next", nil, nil)
+    case "step", "s": err = r.c.Call("stepIn", nil, nil)
+    case "out": err = r.c.Call("stepOut", nil, nil)
+    case "pause": err = r.c.Call("pause", nil, nil)
+    case "stack", "bt": err = r.stack()
+    case "vars", "v": err = r.vars(rest)
+    case "eval", "p": err = r.eval(rest)
+    case "set": err = r.set(rest)
+    case "input": err = r.c.Call("input", p.InputArgs{Text: rest}, nil)
+    case "send": err = r.send(rest)
+    default: err = fmt.Errorf("unknown command %q; try help", cmd)
+    }
+    if err != nil { r.printf("error: %v\n", err) }
+    return true
+}
+
+func (r *repl) launch(program string, stop bool) error {
+    if program == "" { return fmt.Errorf("usage: launch <program>") }
+    abs, err := filepath.Abs(program)
+    if err != nil { return err }
+    r.program = abs
+    return r.c.Call("launch", p.LaunchArgs{Program: abs, StopOnEntry: stop}, nil)
+}
+
+// setBreaks adds (or removes) the breakpoints in spec, `[file:]line...`,
+// and sends each changed file's full set. Removing with no spec clears all.
+func (r *repl) setBreaks(spec string, add bool) error {
+    changed := map[string]bool{}
+    if spec == "" {
+        if add { return fmt.Errorf("usage: break [file:]<line>...") }
+        for f := range r.breaks { r.breaks[f], changed[f] = nil, true }
+    }
+    for _, s := range strings.Fields(spec) {
+        file := r.program
+        if i := strings.LastIndex(s, ":"); i >= 0 {
+            abs, err := filepath.Abs(s[:i])
+            if err != nil { return err }
+            file, s = abs, s[i+1:]
+        }
+        if file == "" { return fmt.Errorf("no program launched; use file:line") }
+        line, err := strconv.Atoi(s)
+        if err != nil { return fmt.Errorf("bad line %q", s) }
+        lines := r.breaks[file][:0:0]
+        for _, l := range r.breaks[file] { if l != line { lines = append(lines, l) } }
+        if add { lines = append(lines, line); sort.Ints(lines) }
+        r.breaks[file], changed[file] = lines, true
+    }
+    files := make([]string, 0, len(changed))
+    for f := range changed { files = append(files, f) }
+    sort.Strings(files)
+    for _, f := range files {
+        var body p.SetBreakpointsBody
+        if err := r.c.Call("setBreakpoints", p.SetBreakpointsArgs{Path: f, Lines: r.breaks[f]}, &body); err != nil { return err }
+        for _, bp := range body.Breakpoints { r.printf("breakpoint %d: %s\n", bp.ID, formatBreakpoint(bp)) }
+        if len(body.Breakpoints) == 0 { r.printf("no breakpoints in %s\n", filepath.Base(f)) }
+    }
+    return nil
+}
+
+func formatBreakpoint(bp p.Breakpoint) string {
+    s := fmt.Sprintf("line %d", bp.Line)
+    if bp.Source != nil && bp.Source.Name != "" { s = bp.Source.Name + ":" + strconv.Itoa(bp.Line) }
+    if !bp.Verified {
+        s += " (unverified"
+        if bp.Message != "" { s += ": " + bp.Message }
+        s += ")"
+    }
+    return s
+}
+
+func (r *repl) stack() error {
+    var body p.StackTraceBody
+    if err := r.c.Call("stackTrace", p.StackTraceArgs{}, &body); err != nil { return err }
+    for i, f := range body.StackFrames {
+        where := fmt.Sprintf("line %d", f.Line)
+        if f.Source != nil && f.Source.Name != "" { where = fmt.Sprintf("%s:%d", f.Source.Name, f.Line) }
+        r.printf("#%d %s  %s\n", i, f.Name, where)
+    }
+    return nil
+}
+
+// vars lists each scope, or the fields of one variablesReference. Runtimes
+// without scopes get their locals from getLocalVariables.
+func (r *repl) vars(ref string) error {
+    if ref != "" {
+        n, err := strconv.Atoi(strings.TrimPrefix(ref, "#"))
+        if err != nil { return fmt.Errorf("usage: vars [ref]") }
+        return r.listVariables("", n)
+    }
+    var scopes p.ScopesBody
+    if err := r.c.Call("scopes", p.ScopesArgs{}, &scopes); err != nil {
+        var locals p.VariablesBody
+        if err := r.c.Call("getLocalVariables", nil, &locals); err != nil { return err }
+        r.printVariables("", locals.Variables)
+        return nil
+    }
+    for _, s := range scopes.Scopes {
+        r.printf("%s:\n", s.Name)
+        if err := r.listVariables("  ", s.VariablesReference); err != nil { return err }
+    }
+    return nil
+}
+
+func (r *repl) listVariables(indent string, ref int) error {
+    var body p.VariablesBody
+    if err := r.c.Call("variables", p.VariablesArgs{VariablesReference: ref}, &body); err != nil { return err }
+    r.printVariables(indent, body.Variables)
+    return nil
+}
+
+func (r *repl) printVariables(indent string, vars []p.Variable) {
+    if len(vars) == 0 { r.printf("%s(none)\n", indent) }
+    for _, v := range vars { r.printf("%s%s\n", indent, formatVariable(v.Name, v.Value, v.Type, v.VariablesReference)) }
+}
+
+func (r *repl) eval(expr string) error {
+    if expr == "" { return fmt.Errorf("usage: eval <expression>") }
+    var body p.EvaluateBody
+    if err := r.c.Call("evaluate", p.EvaluateArgs{Expression: expr}, &body); err != nil { return err }
+    r.printf("%s\n", formatVariable(expr, body.Value, body.Type, body.VariablesReference))
+    return nil
+}
+
+func (r *repl) set(args string) error {
+    expr, value, ok := strings.Cut(args, " ")
+    if !ok { return fmt.Errorf("usage: set <expression> <value>") }
+    var body p.SetVariableBody
+    if err := r.c.Call("setExpression", p.SetExpressionArgs{Expression: expr, Value: strings.TrimSpace(value)}, &body); err != nil { return err }
+    r.printf("%s\n", formatVariable(expr, body.Value, body.Type, body.VariablesReference))
+    return nil
+}
+
+func (r *repl) send(args string) error {
+    command, raw, _ := strings.Cut(args, " ")
+    if command == "" { return fmt.Errorf("usage: send <command> [json]") }
+    var a any
+    if raw = strings.TrimSpace(raw); raw != "" {
+        if err := json.Unmarshal([]byte(raw), &a); err != nil { return fmt.Errorf("arguments: %v", err) }
+    }
+    var body json.RawMessage
+    if err := r.c.Call(command, a, &body); err != nil { return err }
+    if len(body) == 0 { r.printf("ok\n"); return nil }
+    out, _ := json.MarshalIndent(body, "", "  ")
+    r.printf("%s\n", out)
+    return nil
+}
+
+// formatVariable renders `name = value (type)`, with the reference to pass
+// to vars for a structured value.
+func formatVariable(name string, value any, typ string, ref int) string {
+    s := fmt.Sprintf("%s = %s", name, formatValue(value))
+    if typ != "" { s += " (" + typ + ")" }
+    if ref != 0 { s += fmt.Sprintf(" #%d", ref) }
+    return s
+}
+
+// formatValue writes a value the way the mock language would: structured
+// values are lists of {name, value} pairs, shown as arrays when the names
+// are their indexes.
+func formatValue(v any) string {
+    switch v := v.(type) {
+    case nil:
+        return "null"
+    case string:
+        return strconv.Quote(v)
+    case []any:
+        parts, array := make([]string, len(v)), true
+        for i, e := range v {
+            m, _ := e.(map[string]any)
+            name, _ := m["name"].(string)
+            if name != strconv.Itoa(i) { array = false }
+            parts[i] = formatValue(m["value"])
+            if !array { break }
+        }
+        if array { return "[" + strings.Join(parts, ", ") + "]" }
+        for i, e := range v {
+            m, _ := e.(map[string]any)
+            parts[i] = fmt.Sprintf("%v: %s", m["name"], formatValue(m["value"]))
+        }
+        return "{" + strings.Join(parts, ", ") + "}"
+    }
+    return fmt.Sprint(v)
+}
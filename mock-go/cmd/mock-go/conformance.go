@@ -0,0 +1,93 @@
+package main
+
+import (
+    "errors"
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/conformance"
+    "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/server"
+)
+
+// testData is where the extension keeps the programs its tests debug.
+const testData = "vscode-mock-debug/src/tests/data"
+
+// runConformance implements `mock-go conformance [flags] [transcript...]`:
+// it plays the built-in transcripts, or the given files, against a runtime
+// and exits with status 1 when the runtime diverges.
+func runConformance(args []string) {
+    fs := flag.NewFlagSet("conformance", flag.ExitOnError)
+    var (
+        runtime = fs.String("runtime", "", "command line of the runtime to check, e.g. \"node mock-ts/dist/cli.js\" (default: mock-go in-process)")
+        data    = fs.String("data", "", "directory of the test programs (default: "+testData+" above the working directory)")
+        timeout = fs.Duration("timeout", conformance.DefaultTimeout, "how long to wait for each expected message")
+        verbose = fs.Bool("v", false, "list passing transcripts too")
+    )
+    fs.Usage = func() {
+        fmt.Fprintln(fs.Output(), "usage: mock-go conformance [flags] [transcript...]")
+        fs.PrintDefaults()
+    }
+    fs.Parse(args)
+
+    dir, err := dataDir(*data)
+    if err != nil { fatalf("conformance: %v", err) }
+
+    // mock-go's own extensions are only expected of mock-go itself.
+    transcripts := conformance.Builtin()
+    if *runtime == "" { transcripts = append(transcripts, conformance.MockGo()...) }
+    if fs.NArg() > 0 {
+        transcripts = nil
+        for _, file := range fs.Args() {
+            b, err := os.ReadFile(file)
+            if err != nil { fatalf("conformance: %v", err) }
+            t, err := conformance.Parse(filepath.Base(file), b)
+            if err != nil { fatalf("conformance: %v", err) }
+            transcripts = append(transcripts, t)
+        }
+    }
+
+    rt := conformance.InProcess(server.Options{})
+    if *runtime != "" {
+        fields := strings.Fields(*runtime)
+        rt = conformance.Command(fields[0], fields[1:]...)
+    }
+
+    vars := map[string]string{"DATA": filepath.ToSlash(dir)}
+    failed := 0
+    for _, t := range transcripts {
+        res := conformance.Run(rt, t, vars, *timeout)
+        switch {
+        case res.Err != nil:
+            failed++
+            fmt.Printf("ERROR %s: %v\n", t.Name, res.Err)
+        case res.Divergence != nil:
+            failed++
+            fmt.Printf("FAIL  %s: %s", t.Name, res.Divergence)
+        case *verbose:
+            fmt.Printf("PASS  %s\n", t.Name)
+        }
+    }
+    fmt.Printf("%d/%d transcripts passed\n", len(transcripts)-failed, len(transcripts))
+    if failed > 0 { os.Exit(1) }
+}
+
+// dataDir returns dir made absolute, or finds the test programs by walking
+// up from the working directory.
+func dataDir(dir string) (string, error) {
+    if dir != "" { return filepath.Abs(dir) }
+    wd, err := os.Getwd()
+    if err != nil { return "", err }
+    for d := wd; ; d = filepath.Dir(d) {
+        if st, err := os.Stat(filepath.Join(d, testData)); err == nil && st.IsDir() { return filepath.Join(d, testData), nil }
+        if filepath.Dir(d) == d { break }
+    }
+    return "", errors.New("cannot find " + testData + "; pass -data")
+}
+
+func fatalf(format string, args ...any) {
+    fmt.Fprintf(os.Stderr, format+"\n", args...)
+    os.Exit(2)
+}
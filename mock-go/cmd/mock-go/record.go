@@ -0,0 +1,60 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "log"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+
+    "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/server"
+)
+
+// recordTo opens the recording of each session: path itself, or with
+// numbered set (one recording per TCP connection) path-1, path-2, ...
+// before its extension.
+func recordTo(path string, numbered bool) func() io.Writer {
+    var mu sync.Mutex
+    n := 0
+    return func() io.Writer {
+        mu.Lock()
+        n++
+        name := path
+        if numbered { ext := filepath.Ext(path); name = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), n, ext) }
+        mu.Unlock()
+        f, err := os.Create(name)
+        if err != nil { log.Printf("record: %v", err); return nil }
+        return f
+    }
+}
+
+// replay feeds the recording at path through a new session and prints how
+// the output differs; it returns the exit status.
+func replay(path string, setup func(*server.Server)) int {
+    f, err := os.Open(path)
+    if err != nil { log.Printf("replay: %v", err); return 2 }
+    rec, err := server.ReadRecording(f)
+    f.Close()
+    if err != nil { log.Printf("replay: %v", err); return 2 }
+    srv := server.New(rec.Options)
+    setup(srv)
+    mismatches := srv.Replay(rec)
+    for _, m := range mismatches {
+        if m.Request == "" { fmt.Println("before the first request:") } else { fmt.Printf("after %s:\n", m.Request) }
+        for i := 0; i < len(m.Expected) || i < len(m.Got); i++ {
+            switch {
+            case i >= len(m.Got): fmt.Printf("  - %s\n", m.Expected[i])
+            case i >= len(m.Expected): fmt.Printf("  + %s\n", m.Got[i])
+            case server.SameMessage(m.Expected[i], m.Got[i]): fmt.Printf("    %s\n", m.Got[i])
+            default: fmt.Printf("  - %s\n  + %s\n", m.Expected[i], m.Got[i])
+            }
+        }
+    }
+    in := 0
+    for _, e := range rec.Entries { if e.Dir == "in" { in++ } }
+    if len(mismatches) > 0 { fmt.Printf("replay: output differs after %d of %d client lines\n", len(mismatches), in); return 1 }
+    fmt.Printf("replay: %d client lines, output matches\n", in)
+    return 0
+}
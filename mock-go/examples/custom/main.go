@@ -0,0 +1,67 @@
+// Command custom serves the mock runtime on stdio with a project-specific
+// command, a token check and request metrics added on top of the built-in
+// commands:
+//
+//	MOCK_TOKEN=s3cret go run ./examples/custom
+//	{"type":"request","id":1,"command":"initialize","args":{"token":"s3cret"}}
+//	{"type":"request","id":2,"command":"launch","args":{"programText":"a\nb\n","stopOnEntry":true}}
+//	{"type":"request","id":3,"command":"lineCount"}
+//	{"type":"request","id":4,"command":"lineText","args":{"line":1}}
+package main
+
+import (
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "strings"
+    "time"
+
+    "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/protocol"
+    "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/server"
+)
+
+// auth rejects every request of a session until initialize presents the
+// token from MOCK_TOKEN.
+func auth(token string) server.Middleware {
+    return func(next server.Handler) server.Handler {
+        return func(s *server.Session, req protocol.Request) (any, error) {
+            if s.Values["authorized"] == true { return next(s, req) }
+            var args struct{ Token string `json:"token"` }
+            if req.Command != "initialize" || req.Decode(&args) != nil || args.Token != token { return nil, errors.New("not authorized") }
+            s.Values["authorized"] = true
+            return next(s, req)
+        }
+    }
+}
+
+func main() {
+    srv := server.New(server.Options{})
+    logger := log.New(os.Stderr, "", 0)
+    srv.Use(server.Logging(logger), auth(os.Getenv("MOCK_TOKEN")))
+
+    requests := map[string]int{}
+    srv.Use(server.Metrics(func(command string, took time.Duration, err error) {
+        requests[command]++
+        if took > 100*time.Millisecond { logger.Printf("slow %s: %v", command, took) }
+    }))
+
+    srv.Handle("lineCount", func(s *server.Session, req protocol.Request) (any, error) {
+        return map[string]any{"lines": s.Engine.SourceLength()}, nil
+    })
+    // typed arguments: a wrong type fails the request with a message naming the field
+    type lineArgs struct{ Line int `json:"line"` }
+    srv.Handle("lineText", server.Typed(func(s *server.Session, a lineArgs) (any, error) {
+        text, _ := s.Engine.SourceContent(s.Engine.SourceRef())
+        lines := strings.Split(text, "\n")
+        if a.Line < 0 || a.Line >= len(lines) { return nil, fmt.Errorf("no line %d", a.Line) }
+        return map[string]any{"text": lines[a.Line]}, nil
+    }))
+    // a custom event after the response, the way built-in commands report effects
+    srv.Handle("ping", func(s *server.Session, req protocol.Request) (any, error) {
+        s.After(func() { s.Event("pong", map[string]any{"requests": requests["ping"]}) })
+        return nil, nil
+    })
+
+    srv.ServeConn(os.Stdin, os.Stdout)
+}
@@ -0,0 +1,36 @@
+// Command embed runs a mock program with the engine linked in directly:
+// it stops at a breakpoint, prints the local variables and runs to the end.
+package main
+
+import (
+    "fmt"
+
+    "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/engine"
+)
+
+const program = `$count=0
+repeat 3
+  $count=$count+1
+  log(count is $count)
+end
+out(done)
+`
+
+// printer reports the events this example cares about; NopDebugger covers
+// the rest.
+type printer struct{ engine.NopDebugger }
+
+func (printer) OnStopOnBreakpoint(line int, column *int) { fmt.Printf("stopped at line %d\n", line+1) }
+func (printer) OnOutput(o engine.Output)                { fmt.Printf("[%s] %s\n", o.Category, o.Text) }
+func (printer) OnEnd()                                  { fmt.Println("ended") }
+
+func main() {
+    eng := engine.New(printer{})
+    ref := eng.LoadInline("counter.md", []byte(program))
+    eng.SetBreakpoints("", ref, []int{3})
+
+    eng.Continue(false)
+    for _, v := range eng.GetLocalVariables() { fmt.Printf("  %s = %v\n", v.Name, v.Value) }
+    eng.SetBreakpoints("", ref, nil)
+    eng.Continue(false)
+}
@@ -0,0 +1,63 @@
+// Command language plugs a tiny assembler-like language into the engine:
+//
+//	set x 1+2    assign
+//	print x      read and print
+//	fail oops    throw
+//
+// Expressions, variables and stepping are still the engine's.
+package main
+
+import (
+    "fmt"
+    "strings"
+
+    "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/engine"
+)
+
+type asm struct{}
+
+func (asm) Words(ln int, text string) []engine.Word {
+    out := []engine.Word{}
+    i := 0
+    for _, f := range strings.Fields(text) {
+        i += strings.Index(text[i:], f)
+        out = append(out, engine.Word{Name: f, Line: ln, Index: i})
+        i += len(f)
+    }
+    return out
+}
+
+func (asm) Effects(text string) []engine.Effect {
+    f := strings.Fields(text)
+    if len(f) < 2 { return nil }
+    switch f[0] {
+    case "set":
+        if len(f) < 3 { return nil }
+        return []engine.Effect{{Kind: engine.EffectAssign, Name: f[1], Text: strings.Join(f[2:], " ")}}
+    case "print":
+        return []engine.Effect{
+            {Kind: engine.EffectRead, Name: f[1]},
+            {Kind: engine.EffectOutput, Name: "out", Text: f[1] + " = $" + f[1]},
+        }
+    case "fail":
+        return []engine.Effect{{Kind: engine.EffectException, Name: f[1]}}
+    }
+    return nil
+}
+
+func (asm) Block(text string) (kind, cond string) { return "", "" }
+
+type printer struct{ engine.NopDebugger }
+
+func (printer) OnOutput(o engine.Output) { fmt.Println(o.Text) }
+func (printer) OnStopOnException(line int, exception *string, column *int) {
+    fmt.Printf("exception on line %d\n", line+1)
+}
+func (printer) OnEnd() { fmt.Println("ended") }
+
+func main() {
+    eng := engine.New(printer{}, engine.WithLanguage(asm{}))
+    eng.SetExceptionsFilters(nil, true)
+    eng.LoadInline("calc.asm", []byte("set x 1+2\nset y $x*10\nprint y\nfail overflow\nprint x\n"))
+    eng.Continue(false)
+}
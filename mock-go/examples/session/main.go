@@ -0,0 +1,61 @@
+// Command session talks the JSON protocol to an in-process server over
+// pipes, the way an editor talks to `mock-go` over stdio, and prints every
+// message the server sends.
+package main
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "io"
+
+    "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/protocol"
+    "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/server"
+)
+
+func main() {
+    reqR, reqW := io.Pipe()
+    resR, resW := io.Pipe()
+    go func() { server.ServeConn(reqR, resW, server.Options{}); resW.Close() }()
+
+    // pipes have no buffer: requests go out from their own goroutine so that
+    // writing one never waits on the server writing to us
+    out, id := make(chan protocol.Request, 16), 0
+    go func() {
+        enc := json.NewEncoder(reqW)
+        for req := range out { _ = enc.Encode(req) }
+        reqW.Close()
+    }()
+    send := func(command string, args any) {
+        id++
+        req, _ := protocol.NewRequest(id, command, args)
+        out <- req
+    }
+
+    text := "line one\n$x=42\nlog($x)\n"
+    send("launch", protocol.LaunchArgs{ProgramText: &text, StopOnEntry: true})
+    scanner := bufio.NewScanner(resR)
+    for scanner.Scan() {
+        fmt.Println(scanner.Text())
+        var msg struct {
+            Type  string         `json:"type"`
+            ID    int            `json:"id"`
+            Event string         `json:"event"`
+            Body  map[string]any `json:"body"`
+        }
+        if json.Unmarshal(scanner.Bytes(), &msg) != nil { continue }
+        switch {
+        case msg.Type == "response" && msg.ID == 1:
+            // the launch response carries the sourceReference of the inline program
+            ref, _ := msg.Body["sourceReference"].(float64)
+            send("setBreakpoints", protocol.SetBreakpointsArgs{SourceReference: int(ref), Lines: []int{2}})
+        case msg.Event == "stopped" && msg.Body["reason"] == "entry":
+            send("continue", nil)
+        case msg.Event == "stopped":
+            send("stackTrace", nil)
+            send("continue", nil)
+        case msg.Event == "terminated":
+            close(out)
+        }
+    }
+}
@@ -1,4 +1,4 @@
-module mock-go
+module github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go
 
 go 1.21
 
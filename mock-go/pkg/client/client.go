@@ -0,0 +1,160 @@
+// Package client speaks the mock runtime protocol from the debugger's side:
+// Call sends a request and waits for its response, and events are handed
+// to a callback as they arrive. It talks to any runtime, over a TCP
+// connection (Dial), a child process's stdio (Exec) or any reader/writer
+// pair (New).
+package client
+
+import (
+    "bufio"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "os/exec"
+    "sync"
+
+    p "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/protocol"
+)
+
+// Event is an event from the runtime; decode Body into the event's type.
+type Event struct {
+    Name string
+    Body json.RawMessage
+}
+
+// ErrClosed is returned by Call once the runtime has disconnected.
+var ErrClosed = errors.New("runtime disconnected")
+
+// message is any message from the runtime.
+type message struct {
+    Type    string          `json:"type"`
+    ID      int             `json:"id"`
+    Success bool            `json:"success"`
+    Message string          `json:"message"`
+    Event   string          `json:"event"`
+    Body    json.RawMessage `json:"body"`
+}
+
+// Client is a connection to a runtime. Call is safe for concurrent use.
+type Client struct {
+    onEvent func(Event)
+    closer  io.Closer
+
+    wmu sync.Mutex // guards enc
+    enc *json.Encoder
+
+    mu      sync.Mutex // guards the rest
+    nextID  int
+    pending map[int]chan message
+    done    chan struct{}
+}
+
+// New starts a client reading messages from r and writing requests to w.
+// onEvent (which may be nil) runs on the reading goroutine for each event,
+// in order; it must not Call, since responses are read by that goroutine.
+func New(r io.Reader, w io.Writer, onEvent func(Event)) *Client {
+    if onEvent == nil { onEvent = func(Event) {} }
+    c := &Client{onEvent: onEvent, enc: json.NewEncoder(w), pending: map[int]chan message{}, done: make(chan struct{})}
+    go c.read(r)
+    return c
+}
+
+// Dial connects to a runtime serving TCP, such as `mock-go --server`.
+func Dial(addr string, onEvent func(Event)) (*Client, error) {
+    conn, err := net.Dial("tcp", addr)
+    if err != nil { return nil, err }
+    c := New(conn, conn, onEvent)
+    c.closer = conn
+    return c, nil
+}
+
+// Exec starts a runtime that speaks the protocol over stdio. Its stderr
+// goes to stderr, if not nil.
+func Exec(onEvent func(Event), stderr io.Writer, name string, args ...string) (*Client, error) {
+    cmd := exec.Command(name, args...)
+    cmd.Stderr = stderr
+    in, err := cmd.StdinPipe()
+    if err != nil { return nil, err }
+    out, err := cmd.StdoutPipe()
+    if err != nil { return nil, err }
+    if err := cmd.Start(); err != nil { return nil, err }
+    c := New(out, in, onEvent)
+    c.closer = closerFunc(func() error { in.Close(); return cmd.Wait() })
+    return c, nil
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+func (c *Client) read(r io.Reader) {
+    defer func() {
+        c.mu.Lock()
+        defer c.mu.Unlock()
+        close(c.done)
+        for id, ch := range c.pending { close(ch); delete(c.pending, id) }
+    }()
+    sc := bufio.NewScanner(r)
+    sc.Buffer(make([]byte, 0, 1024*1024), 16*1024*1024)
+    for sc.Scan() {
+        var m message
+        if json.Unmarshal(sc.Bytes(), &m) != nil { continue }
+        switch m.Type {
+        case "event":
+            c.onEvent(Event{Name: m.Event, Body: m.Body})
+        case "response":
+            c.mu.Lock()
+            ch, ok := c.pending[m.ID]
+            delete(c.pending, m.ID)
+            c.mu.Unlock()
+            if ok { ch <- m }
+        }
+    }
+}
+
+// Call sends command with args (nil for none), waits for the response and
+// decodes its body into body (nil to ignore it). A failure response is
+// returned as an error with the runtime's message.
+func (c *Client) Call(command string, args, body any) error {
+    ch := make(chan message, 1)
+    c.mu.Lock()
+    select {
+    case <-c.done: c.mu.Unlock(); return ErrClosed
+    default:
+    }
+    c.nextID++
+    id := c.nextID
+    c.pending[id] = ch
+    c.mu.Unlock()
+
+    req, err := p.NewRequest(id, command, args)
+    if err == nil {
+        c.wmu.Lock()
+        err = c.enc.Encode(req)
+        c.wmu.Unlock()
+    }
+    if err != nil {
+        c.mu.Lock()
+        delete(c.pending, id)
+        c.mu.Unlock()
+        return err
+    }
+
+    m, ok := <-ch
+    if !ok { return ErrClosed }
+    if !m.Success { return fmt.Errorf("%s: %s", command, m.Message) }
+    if body != nil && len(m.Body) > 0 { return json.Unmarshal(m.Body, body) }
+    return nil
+}
+
+// Done is closed when the runtime disconnects.
+func (c *Client) Done() <-chan struct{} { return c.done }
+
+// Close closes the connection, or ends the runtime started by Exec. It
+// does not send disconnect.
+func (c *Client) Close() error {
+    if c.closer == nil { return nil }
+    return c.closer.Close()
+}
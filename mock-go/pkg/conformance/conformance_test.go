@@ -0,0 +1,26 @@
+package conformance
+
+import (
+    "path/filepath"
+    "testing"
+    "time"
+
+    "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/server"
+)
+
+// testData holds the programs the extension's tests debug.
+const testData = "../../../vscode-mock-debug/src/tests/data"
+
+func TestBuiltin(t *testing.T) {
+    dir, err := filepath.Abs(testData)
+    if err != nil { t.Fatal(err) }
+    vars := map[string]string{"DATA": filepath.ToSlash(dir)}
+    rt := InProcess(server.Options{})
+    for _, tr := range append(Builtin(), MockGo()...) {
+        t.Run(tr.Name, func(t *testing.T) {
+            res := Run(rt, tr, vars, 2*time.Second)
+            if res.Err != nil { t.Fatal(res.Err) }
+            if res.Divergence != nil { t.Fatal(res.Divergence) }
+        })
+    }
+}
@@ -0,0 +1,8 @@
+// Package conformance checks that a runtime speaks the protocol the way
+// mock-go does. A transcript scripts a session as requests to send and
+// responses and events to expect; Run plays one against a Runtime, either
+// mock-go's server in-process or any runtime binary over stdio, and reports
+// where the runtime diverged. The built-in transcripts debug the programs of
+// the extension's tests (vscode-mock-debug/src/tests/data), found through the
+// DATA variable.
+package conformance
@@ -0,0 +1,245 @@
+package conformance
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "io"
+    "os/exec"
+    "reflect"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/server"
+)
+
+// DefaultTimeout is how long Run waits for an expected message.
+const DefaultTimeout = 2 * time.Second
+
+// Runtime starts a fresh session of a runtime: requests are written to in,
+// messages read from out, and stop ends the session.
+type Runtime func() (in io.WriteCloser, out io.Reader, stop func(), err error)
+
+// InProcess runs mock-go's server in this process.
+func InProcess(opts server.Options) Runtime {
+    return func() (io.WriteCloser, io.Reader, func(), error) {
+        inR, inW := io.Pipe()
+        outR, outW := io.Pipe()
+        go func() {
+            server.New(opts).ServeConn(inR, outW)
+            outW.Close()
+        }()
+        return inW, outR, func() { inW.Close(); outR.Close() }, nil
+    }
+}
+
+// Command runs a runtime binary speaking the protocol over stdio, such as
+// `mock-go`, `node dist/cli.js` or `dotnet MockRuntime.Cli.dll`.
+func Command(name string, args ...string) Runtime {
+    return func() (io.WriteCloser, io.Reader, func(), error) {
+        cmd := exec.Command(name, args...)
+        in, err := cmd.StdinPipe()
+        if err != nil { return nil, nil, nil, err }
+        out, err := cmd.StdoutPipe()
+        if err != nil { return nil, nil, nil, err }
+        if err := cmd.Start(); err != nil { return nil, nil, nil, err }
+        stop := func() {
+            in.Close()
+            done := make(chan struct{})
+            go func() { cmd.Wait(); close(done) }()
+            select {
+            case <-done:
+            case <-time.After(time.Second): cmd.Process.Kill(); <-done
+            }
+        }
+        return in, out, stop, nil
+    }
+}
+
+// Result is the outcome of running one transcript. Err is set when the
+// session could not run at all; Divergence when the runtime answered
+// differently from the transcript.
+type Result struct {
+    Name       string
+    Err        error
+    Divergence *Divergence
+}
+
+// Passed reports whether the runtime followed the transcript.
+func (r Result) Passed() bool { return r.Err == nil && r.Divergence == nil }
+
+// Divergence is the first step a runtime did not follow: the expectation at
+// Line of the transcript, and the messages received so far that no earlier
+// step accounted for.
+type Divergence struct {
+    Line     int
+    Expected string
+    Received []string
+}
+
+func (d *Divergence) String() string {
+    var b strings.Builder
+    fmt.Fprintf(&b, "line %d: expected %s\n", d.Line, d.Expected)
+    if len(d.Received) == 0 { b.WriteString("  received nothing\n") }
+    for _, m := range d.Received { fmt.Fprintf(&b, "  received %s\n", m) }
+    return b.String()
+}
+
+// message is one line a runtime wrote; value is nil when it is not JSON.
+type message struct {
+    raw      string
+    value    map[string]any
+    consumed bool
+}
+
+// inbox collects a runtime's messages as they arrive.
+type inbox struct {
+    mu     sync.Mutex
+    msgs   []*message
+    closed bool
+    notify chan struct{}
+}
+
+func (b *inbox) read(r io.Reader) {
+    sc := bufio.NewScanner(r)
+    sc.Buffer(make([]byte, 0, 1024*1024), 1024*1024)
+    for sc.Scan() {
+        line := strings.TrimSpace(sc.Text())
+        if line == "" { continue }
+        m := &message{raw: line}
+        _ = json.Unmarshal([]byte(line), &m.value)
+        b.mu.Lock()
+        b.msgs = append(b.msgs, m)
+        b.mu.Unlock()
+        b.wake()
+    }
+    b.mu.Lock()
+    b.closed = true
+    b.mu.Unlock()
+    b.wake()
+}
+
+func (b *inbox) wake() {
+    select {
+    case b.notify <- struct{}{}:
+    default:
+    }
+}
+
+// take waits until a message not consumed yet satisfies match, consumes it
+// and returns true; it gives up at the deadline or when the runtime exits.
+func (b *inbox) take(match func(map[string]any) bool, deadline time.Time) bool {
+    timer := time.NewTimer(time.Until(deadline))
+    defer timer.Stop()
+    for {
+        b.mu.Lock()
+        for _, m := range b.msgs {
+            if !m.consumed && m.value != nil && match(m.value) {
+                m.consumed = true
+                b.mu.Unlock()
+                return true
+            }
+        }
+        closed := b.closed
+        b.mu.Unlock()
+        if closed { return false }
+        select {
+        case <-b.notify:
+        case <-timer.C: return false
+        }
+    }
+}
+
+// unconsumed lists the raw messages no step has matched.
+func (b *inbox) unconsumed() []string {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    out := []string{}
+    for _, m := range b.msgs { if !m.consumed { out = append(out, m.raw) } }
+    return out
+}
+
+// Run plays t against a new session of rt. Requests are sent in order; each
+// expectation waits up to timeout for a matching message. Messages may
+// arrive in any order and extra ones are ignored, since runtimes race their
+// events against responses and differ in which optional events they send.
+// Run stops at the first expectation that is not met.
+func Run(rt Runtime, t Transcript, vars map[string]string, timeout time.Duration) Result {
+    res := Result{Name: t.Name}
+    if timeout <= 0 { timeout = DefaultTimeout }
+    in, out, stop, err := rt()
+    if err != nil { res.Err = err; return res }
+    defer stop()
+    box := &inbox{notify: make(chan struct{}, 1)}
+    go box.read(out)
+    enc := json.NewEncoder(in)
+
+    id := 0
+    for _, s := range t.Steps {
+        var v any
+        if s.JSON != "" {
+            text, err := expand(s.JSON, vars)
+            if err == nil { err = json.Unmarshal([]byte(text), &v) }
+            if err != nil { res.Err = fmt.Errorf("%s:%d: %v", t.Name, s.Line, err); return res }
+        }
+        if s.Send {
+            id++
+            req := map[string]any{"type": "request", "id": id, "command": s.Name}
+            if v != nil { req["args"] = v }
+            if err := enc.Encode(req); err != nil { res.Err = fmt.Errorf("%s:%d: %v", t.Name, s.Line, err); return res }
+            continue
+        }
+        want, reqID := v, id
+        match := func(m map[string]any) bool {
+            if s.Event { return m["type"] == "event" && m["event"] == s.Name && subset(want, m["body"]) }
+            return m["type"] == "response" && m["id"] == float64(reqID) && subset(want, m)
+        }
+        if !box.take(match, time.Now().Add(timeout)) {
+            res.Divergence = &Divergence{Line: s.Line, Expected: s.Text, Received: box.unconsumed()}
+            return res
+        }
+    }
+    return res
+}
+
+// expand replaces ${NAME} in text with the JSON-escaped variable, for use
+// inside a JSON string.
+func expand(text string, vars map[string]string) (string, error) {
+    var err error
+    out := varRe.ReplaceAllStringFunc(text, func(ref string) string {
+        name := varRe.FindStringSubmatch(ref)[1]
+        val, ok := vars[name]
+        if !ok { err = fmt.Errorf("undefined variable %s", name); return "" }
+        b, _ := json.Marshal(val)
+        return string(b[1 : len(b)-1])
+    })
+    return out, err
+}
+
+// subset reports whether got contains want: objects match key by key,
+// arrays element by element at the same length, anything else by equality.
+// A nil want matches anything.
+func subset(want, got any) bool {
+    switch w := want.(type) {
+    case nil:
+        return true
+    case map[string]any:
+        g, ok := got.(map[string]any)
+        if !ok { return false }
+        for k, wv := range w {
+            gv, ok := g[k]
+            if !ok { return false }
+            if wv == nil { if gv != nil { return false }; continue }
+            if !subset(wv, gv) { return false }
+        }
+        return true
+    case []any:
+        g, ok := got.([]any)
+        if !ok || len(g) != len(w) { return false }
+        for i := range w { if !subset(w[i], g[i]) { return false } }
+        return true
+    }
+    return reflect.DeepEqual(want, got)
+}
+
@@ -0,0 +1,109 @@
+package conformance
+
+import (
+    "bufio"
+    "bytes"
+    "embed"
+    "encoding/json"
+    "fmt"
+    "path"
+    "regexp"
+    "sort"
+    "strings"
+)
+
+// A transcript scripts one session:
+//
+//	# comment
+//	-> launch {"program": "${DATA}/test.md", "stopOnEntry": true}
+//	<- response {"success": true}
+//	<- event stopped {"reason": "entry", "line": 0}
+//
+// `->` sends a request (arguments optional). `<- response` expects the
+// response to the last request and `<- event <name>` an event; the JSON
+// after either is matched as a subset of the whole response or of the event
+// body. ${NAME} is replaced by the variable NAME before the JSON is parsed.
+
+// Transcript is a parsed transcript.
+type Transcript struct {
+    Name  string
+    Steps []Step
+}
+
+// Step sends a request or expects a message. Name is the command to send,
+// "response", or the name of the expected event; JSON is the arguments or
+// the expected subset, with variables unexpanded.
+type Step struct {
+    Line  int
+    Text  string
+    Send  bool
+    Event bool
+    Name  string
+    JSON  string
+}
+
+// varRe matches a ${NAME} variable reference.
+var varRe = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+//go:embed transcripts/*.txt transcripts/mock-go/*.txt
+var builtin embed.FS
+
+// Builtin returns the transcripts shipped with the harness, by name. They
+// only expect behavior every runtime shares.
+func Builtin() []Transcript { return embedded("transcripts", "") }
+
+// MockGo returns the transcripts of behavior only mock-go has, such as its
+// lazy breakpoint verification. Other runtimes are not checked against them.
+func MockGo() []Transcript { return embedded("transcripts/mock-go", "mock-go/") }
+
+func embedded(dir, prefix string) []Transcript {
+    entries, _ := builtin.ReadDir(dir)
+    out := []Transcript{}
+    for _, e := range entries {
+        if e.IsDir() { continue }
+        data, _ := builtin.ReadFile(path.Join(dir, e.Name()))
+        t, err := Parse(prefix+strings.TrimSuffix(e.Name(), ".txt"), data)
+        if err != nil { panic(err) }
+        out = append(out, t)
+    }
+    sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+    return out
+}
+
+// Parse reads a transcript. Variables stay unexpanded until Run.
+func Parse(name string, data []byte) (Transcript, error) {
+    t := Transcript{Name: name}
+    sc := bufio.NewScanner(bytes.NewReader(data))
+    for n := 1; sc.Scan(); n++ {
+        line := strings.TrimSpace(sc.Text())
+        if line == "" || strings.HasPrefix(line, "#") { continue }
+        dir, rest, _ := strings.Cut(line, " ")
+        s := Step{Line: n, Text: line}
+        switch dir {
+        case "->":
+            s.Send = true
+            s.Name, rest, _ = strings.Cut(strings.TrimSpace(rest), " ")
+        case "<-":
+            kind, tail, _ := strings.Cut(strings.TrimSpace(rest), " ")
+            switch kind {
+            case "response": s.Name, rest = kind, tail
+            case "event":
+                s.Event = true
+                s.Name, rest, _ = strings.Cut(strings.TrimSpace(tail), " ")
+            default: return t, fmt.Errorf("%s:%d: expected `<- response` or `<- event <name>`", name, n)
+            }
+        default:
+            return t, fmt.Errorf("%s:%d: a step starts with -> or <-", name, n)
+        }
+        if s.Name == "" { return t, fmt.Errorf("%s:%d: missing command or event name", name, n) }
+        if rest = strings.TrimSpace(rest); rest != "" {
+            // check the JSON now with variables blanked; Run parses it again
+            if err := json.Unmarshal([]byte(varRe.ReplaceAllString(rest, "")), new(any)); err != nil {
+                return t, fmt.Errorf("%s:%d: %v", name, n, err)
+            }
+            s.JSON = rest
+        }
+        t.Steps = append(t.Steps, s)
+    }
+    return t, sc.Err()
+}
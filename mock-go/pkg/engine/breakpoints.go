@@ -0,0 +1,96 @@
+package engine
+
+import (
+    "fmt"
+
+    "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/protocol"
+)
+
+type Breakpoint struct {
+    ID       int
+    Line     int
+    Column   *int
+    Verified bool
+    Message  string
+    // RequestedLine is where the client asked for the breakpoint; Line
+    // differs from it when the breakpoint was relocated.
+    RequestedLine int
+    // Path is the breakpoint's file, or empty for an in-memory program
+    // identified by SourceRef.
+    Path      string
+    SourceRef int
+}
+
+// Body renders the breakpoint in its wire form.
+func (bp Breakpoint) Body() protocol.Breakpoint {
+    b := protocol.Breakpoint{ID: bp.ID, Line: bp.Line, Column: bp.Column, Verified: bp.Verified, Message: bp.Message}
+    if bp.SourceRef > 0 {
+        b.Source = &protocol.Source{SourceReference: bp.SourceRef}
+    } else if bp.Path != "" {
+        b.Source = &protocol.Source{Name: basename(bp.Path), Path: bp.Path}
+    }
+    return b
+}
+
+// SetBreakpoints replaces the breakpoints of a file, or of an in-memory
+// program when ref is a sourceReference. Breakpoints on lines that were
+// already set keep their id and verification; the others are reported as
+// new or removed.
+func (e *Engine) SetBreakpoints(path string, ref int, lines []int) []protocol.Breakpoint {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    key := sourceKey(path, ref)
+    if ref > 0 { path = "" } else { path = abs(path) }
+    // a copy: removing matches must not shift the stored list
+    old := append([]Breakpoint(nil), e.bps[key]...)
+    list, res := make([]Breakpoint, 0, len(lines)), make([]protocol.Breakpoint, 0, len(lines))
+    for _, l := range lines {
+        bp, kept := Breakpoint{}, false
+        for i := range old {
+            if old[i].RequestedLine == l { bp, kept = old[i], true; old = append(old[:i], old[i+1:]...); break }
+        }
+        if !kept {
+            bp = Breakpoint{ID: e.nextBpID, RequestedLine: l, Path: path, SourceRef: ref}
+            e.nextBpID++
+        }
+        line, verified, msg := e.placeBreakpoint(key, l)
+        if kept && line == bp.Line && bp.Verified { verified, msg = true, "" }
+        bp.Line, bp.Verified, bp.Message = line, verified, msg
+        list = append(list, bp)
+        if !kept { e.dbg.OnBreakpoint("new", bp) }
+        res = append(res, bp.Body())
+    }
+    e.bps[key] = list
+    for _, bp := range old { e.dbg.OnBreakpoint("removed", bp) }
+    return res
+}
+
+// SetRelocateBreakpoints turns moving breakpoints off non-executable lines on or off.
+func (e *Engine) SetRelocateBreakpoints(on bool) { e.relocate = on }
+
+// placeBreakpoint decides where a breakpoint requested at line goes. With
+// relocation enabled a non-executable line moves to the next executable one.
+// Unverified breakpoints come with a message explaining why.
+func (e *Engine) placeBreakpoint(path string, line int) (int, bool, string) {
+    if e.verifyLine(path, line) { return line, true, "" }
+    if e.relocate && line >= 0 {
+        for l := line + 1; l < len(e.sourceLines); l++ {
+            if e.verifyLine(path, l) { return l, true, "" }
+        }
+    }
+    if line < 0 || line >= len(e.sourceLines) { return line, false, fmt.Sprintf("line %d is outside the program", line) }
+    return line, false, fmt.Sprintf("line %d has no executable code", line)
+}
+
+// replaceBreakpoints places the loaded program's breakpoints again after its
+// text changed and reports every breakpoint that moved or changed state.
+func (e *Engine) replaceBreakpoints() {
+    key := e.sourceKey()
+    list := e.bps[key]
+    for i, bp := range list {
+        line, verified, msg := e.placeBreakpoint(key, bp.RequestedLine)
+        if line == bp.Line && verified == bp.Verified { continue }
+        list[i].Line, list[i].Verified, list[i].Message = line, verified, msg
+        e.dbg.OnBreakpoint("changed", list[i])
+    }
+}
@@ -0,0 +1,86 @@
+package engine
+
+import (
+    "regexp"
+    "sort"
+    "strings"
+
+    "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/protocol"
+)
+
+// builtins are the functions and keywords of the mock language, with the
+// text inserted for each.
+var builtins = []struct{ Label, Text string }{
+    {"log", "log()"},
+    {"prio", "prio()"},
+    {"out", "out()"},
+    {"err", "err()"},
+    {"group", "group()"},
+    {"endgroup", "endgroup()"},
+    {"telemetry", "telemetry()"},
+    {"read", "read()"},
+    {"include", "include()"},
+    {"exception", "exception()"},
+    {"global", "global $"},
+}
+
+var (
+    memberTailRe = regexp.MustCompile(`\$([a-zA-Z][a-zA-Z0-9_]*(?:\.[a-zA-Z0-9_]+|\[[^\]]+\])*)\.([a-zA-Z0-9_]*)$`)
+    varTailRe    = regexp.MustCompile(`\$([a-zA-Z0-9_]*)$`)
+    wordTailRe   = regexp.MustCompile(`[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// Completions proposes targets for text with the cursor at column
+// (zero-based). Each target carries the start/length of the text it
+// replaces.
+func (e *Engine) Completions(text string, column int) []protocol.CompletionItem {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    if column < 0 || column > len(text) { column = len(text) }
+    prefix := text[:column]
+    out := []protocol.CompletionItem{}
+    add := func(label, insert, typ, partial string) {
+        if !strings.HasPrefix(strings.ToLower(label), strings.ToLower(partial)) { return }
+        item := protocol.CompletionItem{Label: label, Type: typ, Start: column - len(partial), Length: len(partial)}
+        if insert != label { item.Text = insert }
+        if i := strings.Index(insert, "()"); i >= 0 { item.SelectionStart = i + 1 }
+        out = append(out, item)
+    }
+
+    // fields of a structured value after `$name.`
+    if m := memberTailRe.FindStringSubmatch(prefix); m != nil {
+        path, err := e.lvalue("$" + m[1])
+        if err != nil { return out }
+        v, _ := e.valueAt(path)
+        fields, _ := v.([]map[string]any)
+        for _, f := range fields {
+            name, _ := f["name"].(string)
+            add(name, name, "property", m[2])
+        }
+        return out
+    }
+
+    // variable names after `$`
+    if m := varTailRe.FindStringSubmatch(prefix); m != nil {
+        for _, name := range e.visibleNames() { add(name, name, "variable", m[1]) }
+        return out
+    }
+
+    partial := wordTailRe.FindString(prefix)
+    for _, b := range builtins { add(b.Label, b.Text, "function", partial) }
+    if partial == "" {
+        for _, name := range e.visibleNames() { add("$"+name, "$"+name, "variable", "") }
+    }
+    return out
+}
+
+// visibleNames lists locals and the globals they do not shadow.
+func (e *Engine) visibleNames() []string {
+    seen := map[string]bool{}
+    names := []string{}
+    for _, m := range []map[string]any{e.locals, e.globals} {
+        for k := range m { if !seen[k] { seen[k] = true; names = append(names, k) } }
+    }
+    sort.Strings(names)
+    return names
+}
@@ -0,0 +1,137 @@
+package engine
+
+import "strings"
+
+// Control flow of the mock language. A block starts with a header line
+//
+//    if <condition> / while <condition> / repeat <count>
+//
+// and ends with a line `end`; an if block may contain a line `else` (the
+// Language decides which lines these are). The
+// `else` and `end` lines are not statements: execution never stops on them.
+// Keywords without a matching `end` are ordinary lines.
+type block struct {
+    kind string // "if", "while" or "repeat"
+    cond string // condition, or the repeat count
+    head int
+    els  int // else line, or -1
+    end  int
+}
+
+// maxIncludeSteps bounds the lines an included file may run, since a loop
+// in it cannot be paused or cancelled.
+const maxIncludeSteps = 100000
+
+// flow is the control-flow state of one file: its blocks by header, else
+// and end line, the loops currently running and where to continue after
+// the header just executed.
+type flow struct {
+    lines  []string
+    blocks map[int]*block
+    // active loops by header line; the value is the number of repeat
+    // iterations left (unused for while)
+    loops map[int]int
+    jump  int
+}
+
+func newFlow(lang Language, lines []string) *flow {
+    f := &flow{lines: lines, blocks: map[int]*block{}, loops: map[int]int{}, jump: -1}
+    var open []*block
+    for i, l := range lines {
+        kind, cond := lang.Block(strings.TrimSpace(l))
+        switch kind {
+        case "if", "while", "repeat":
+            open = append(open, &block{kind: kind, cond: cond, head: i, els: -1, end: -1})
+            continue
+        case "":
+            continue
+        }
+        if len(open) == 0 { continue }
+        b := open[len(open)-1]
+        switch {
+        case kind == "else" && b.kind == "if" && b.els < 0:
+            b.els = i
+        case kind == "end":
+            open = open[:len(open)-1]
+            b.end = i
+            f.blocks[b.head], f.blocks[b.end] = b, b
+            if b.els >= 0 { f.blocks[b.els] = b }
+        }
+    }
+    return f
+}
+
+// isMarker reports whether ln is the else or end line of a block.
+func (f *flow) isMarker(ln int) bool {
+    b, ok := f.blocks[ln]
+    return ok && ln != b.head
+}
+
+// next returns the line executed after ln: the target chosen by a header,
+// else the following line, passing over blank lines and else/end markers.
+// The end of a loop leads back to its header.
+func (f *flow) next(ln int) int {
+    next := ln + 1
+    if f.jump >= 0 { next, f.jump = f.jump, -1 }
+    for next < len(f.lines) {
+        if b, ok := f.blocks[next]; ok && next != b.head {
+            // an else reached from the then branch, or the end of a block
+            if next == b.end && b.kind != "if" { return b.head }
+            next = b.end + 1
+            continue
+        }
+        if strings.TrimSpace(f.lines[next]) != "" { return next }
+        next++
+    }
+    return next
+}
+
+// enter evaluates the header at ln, if any, and records where execution
+// continues: the body, the else branch or the line after the block.
+func (e *Engine) enter(f *flow, ln int) {
+    b, ok := f.blocks[ln]
+    if !ok || ln != b.head { return }
+    taken := false
+    switch b.kind {
+    case "if", "while":
+        taken = e.truthy(b.cond)
+    case "repeat":
+        if _, running := f.loops[ln]; !running { f.loops[ln] = e.count(b.cond) }
+        taken = f.loops[ln] > 0
+        f.loops[ln]--
+    }
+    switch {
+    case taken:
+        if b.kind != "if" { if _, running := f.loops[ln]; !running { f.loops[ln] = 0 } }
+        f.jump = ln + 1
+    case b.kind == "if" && b.els >= 0:
+        f.jump = b.els + 1
+    default:
+        delete(f.loops, ln)
+        f.jump = b.end + 1
+    }
+}
+
+// loopAt returns the loop whose header is ln and whether it was running
+// before ln executes.
+func (f *flow) loopAt(ln int) (b *block, running bool) {
+    b, ok := f.blocks[ln]
+    if !ok || ln != b.head || b.kind == "if" { return nil, false }
+    _, running = f.loops[ln]
+    return b, running
+}
+
+// truthy evaluates a condition; conditions that are not expressions, or
+// fail to evaluate, are false.
+func (e *Engine) truthy(cond string) bool {
+    v, n, _, err := e.evaluate(cond)
+    return n > 0 && err == nil && isTruthy(v)
+}
+
+// count evaluates the count of a repeat block; anything that is not a
+// positive integer repeats zero times.
+func (e *Engine) count(t string) int {
+    v, _, _, err := e.evaluate(t)
+    if n, ok := v.(int); ok && n > 0 && err == nil { return n }
+    return 0
+}
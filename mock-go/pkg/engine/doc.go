@@ -0,0 +1,6 @@
+// Package engine runs mock debug programs: it loads program text, executes
+// it line by line and reports stops, output and state changes through the
+// Debugger interface. Breakpoints, stepping, variables and memory are
+// driven by the Engine methods; the language itself is pluggable through
+// Language, with Markdown as the default.
+package engine
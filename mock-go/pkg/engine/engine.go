@@ -0,0 +1,601 @@
+package engine
+
+import (
+    "bufio"
+    "errors"
+    "fmt"
+    "path/filepath"
+    "runtime"
+    "strings"
+    "sync"
+
+    "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/protocol"
+)
+
+type Debugger interface {
+    OnStopOnEntry(line int, column *int)
+    OnStopOnStep(line int, column *int)
+    OnStopOnBreakpoint(line int, column *int)
+    OnStopOnException(line int, exception *string, column *int)
+    OnStopOnDataBreakpoint(line int, column *int)
+    OnStopOnInstructionBreakpoint(line int, column *int)
+    OnStopOnPause(line int, column *int)
+    OnStopOnGoto(line int, column *int)
+    OnBreakpoint(reason string, bp Breakpoint)
+    OnOutput(o Output)
+    OnLoadedSource(reason string, source protocol.Source)
+    OnModule(reason string, module protocol.Module)
+    OnInvalidated(areas []string)
+    OnProgressStart(id, title string, cancellable bool)
+    OnProgressUpdate(id string, percentage int, message string)
+    OnProgressEnd(id, message string)
+    OnEnd()
+}
+
+type Word struct {
+    Name  string
+    Line  int
+    Index int
+}
+
+type Engine struct {
+    dbg Debugger
+
+    sourceFile  string
+    sourceRef   int
+    sourceName  string
+    sourceLines []string
+
+    // in-memory programs by sourceReference
+    inline        map[int]inlineSource
+    nextSourceRef int
+
+    // loaded program units, the program first
+    units        []unit
+    nextModuleID int
+
+    currentLine  int
+    currentCol   *int
+    instruction  int
+    instructions []Word
+    starts       []int
+    ends         []int
+    flow         *flow
+
+    // front end turning lines into words and side-effects
+    lang Language
+
+    nextBpID int
+    bps      map[string][]Breakpoint
+    relocate bool
+
+    namedException  *string
+    otherExceptions bool
+
+    dataBps   map[string]string
+    instrBps  map[int]struct{}
+
+    variables map[string]struct{}
+    locals    map[string]any
+    globals   map[string]any
+    frames    []map[string]any
+
+    // variablesReference allocation for structured values
+    refs     map[string]int
+    refPaths map[int]varPath
+    nextRef  int
+
+    // memory regions backing primitive variables
+    mems     map[string]int
+    memPaths map[int]varPath
+
+    // execMu guards the program state. Runs hold it while executing a line
+    // and the inspecting and editing methods take it, so requests handled
+    // during a run see the state between two lines.
+    execMu sync.Mutex
+    // runs started by Go
+    active runState
+
+    // program input consumed by read(); guarded by inputMu
+    inputMu     sync.Mutex
+    inputCond   *sync.Cond
+    input       []string
+    inputClosed bool
+
+    // launch environment
+    cwd          string
+    noDebug      bool
+    includeDepth int
+
+    // long operations reported to the client
+    progress progress
+
+    // output groups opened by group(name) and not yet ended
+    groups int
+
+    paused bool
+}
+
+// New creates an engine reporting to d, configured by opts.
+func New(d Debugger, opts ...Option) *Engine {
+    e := &Engine{
+        dbg:        d,
+        currentLine: 0,
+        bps:        map[string][]Breakpoint{},
+        dataBps:    map[string]string{},
+        instrBps:   map[int]struct{}{},
+        variables:  map[string]struct{}{},
+        locals:     map[string]any{},
+        globals:    map[string]any{},
+        refs:       map[string]int{},
+        refPaths:   map[int]varPath{},
+        nextRef:    GlobalsRef + 1,
+        mems:       map[string]int{},
+        memPaths:   map[int]varPath{},
+        inline:     map[int]inlineSource{},
+        flow:       newFlow(Markdown{}, nil),
+        lang:       Markdown{},
+        nextSourceRef: 1,
+        nextModuleID: 1,
+        nextBpID:   1,
+    }
+    e.inputCond = sync.NewCond(&e.inputMu)
+    e.active.idle = sync.NewCond(&e.active.mu)
+    for _, o := range opts { o(e) }
+    return e
+}
+
+func (e *Engine) SourceFile() string { return e.sourceFile }
+func (e *Engine) SourceLength() int { return len(e.sourceLines) }
+
+func (e *Engine) LoadSource(path string, contents []byte) {
+    e.sourceFile = abs(path)
+    e.sourceRef = 0
+    e.sourceName = basename(e.sourceFile)
+    e.parse(contents)
+    e.announceProgram(string(contents))
+    e.replaceBreakpoints()
+}
+
+// parse splits contents into lines and instructions and rewinds execution.
+func (e *Engine) parse(contents []byte) {
+    e.sourceLines = splitLines(string(contents))
+    e.flow = newFlow(e.lang, e.sourceLines)
+    e.currentLine = 0
+    e.currentCol = nil
+    e.instructions = e.instructions[:0]
+    e.starts = e.starts[:0]
+    e.ends = e.ends[:0]
+    id, step := "", len(e.sourceLines)/10
+    if len(e.sourceLines) >= loadProgressLines {
+        id = e.progress.start("load")
+        e.dbg.OnProgressStart(id, "Loading "+e.sourceName, false)
+    }
+    for i, line := range e.sourceLines {
+        e.starts = append(e.starts, len(e.instructions))
+        words := e.lang.Words(i, line)
+        e.instructions = append(e.instructions, words...)
+        e.ends = append(e.ends, len(e.instructions))
+        if id != "" && (i+1)%step == 0 { e.dbg.OnProgressUpdate(id, e.percent(i), itoa(i+1)+" lines") }
+    }
+    if id != "" { e.progress.end(); e.dbg.OnProgressEnd(id, "") }
+    if len(e.starts) > 0 {
+        e.instruction = e.starts[0]
+    } else { e.instruction = 0 }
+}
+
+func (e *Engine) Pause() {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    if e.paused {
+        return
+    }
+    e.paused = true
+    // if not running a loop, emit immediately
+    e.dbg.OnOutput(Output{Category: "stdout", Text: strings.TrimSpace(e.getLine(e.currentLine)), File: e.sourceFile, Line: e.currentLine})
+    e.dbg.OnStopOnPause(e.currentLine, e.currentCol)
+}
+
+func (e *Engine) Continue(reverse bool) {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    // normalize instruction at start/end of current line
+    if e.currentLine >= 0 && e.currentLine < len(e.starts) {
+        if reverse {
+            end := e.ends[e.currentLine]
+            if end > 0 { e.instruction = end-1 } else { e.instruction = 0 }
+        } else {
+            e.instruction = e.starts[e.currentLine]
+        }
+    }
+    e.paused = false
+    e.run(reverse, nil)
+}
+
+// run executes lines until the program stops or ends, or until inside
+// reports that execution left the region being stepped over. It reports
+// whether a stop or the end was reported.
+func (e *Engine) run(reverse bool, inside func() bool) bool {
+    meter := &runMeter{e: e}
+    defer meter.done()
+    for {
+        e.yield()
+        if e.halted() { return true }
+        if inside != nil && !inside() {
+            return false
+        }
+        if e.paused || meter.step() {
+            e.paused = false
+            meter.done()
+            e.dbg.OnStopOnPause(e.currentLine, e.currentCol)
+            return true
+        }
+        if e.executeLine(e.currentLine, reverse) {
+            return true
+        }
+        if e.updateCurrentLine(reverse) {
+            e.closeGroups()
+            e.dbg.OnEnd()
+            return true
+        }
+        if e.findNextStatement(reverse) {
+            return true
+        }
+    }
+}
+
+// yield lets methods waiting for execMu in between two lines of a run.
+func (e *Engine) yield() {
+    e.execMu.Unlock()
+    runtime.Gosched()
+    e.execMu.Lock()
+}
+
+func (e *Engine) Next(reverse bool) {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    if e.currentLine >= 0 && e.currentLine < len(e.starts) {
+        if reverse {
+            end := e.ends[e.currentLine]
+            if end > 0 { e.instruction = end-1 } else { e.instruction = 0 }
+        } else {
+            e.instruction = e.starts[e.currentLine]
+        }
+    }
+    ln := e.currentLine
+    loop, running := e.flow.loopAt(ln)
+    if e.executeLine(ln, reverse) || e.halted() { return }
+    if !e.updateCurrentLine(reverse) {
+        if e.findNextStatement(reverse) { return }
+        // stepping over the header of a loop runs the whole loop
+        if loop != nil && !running && !reverse {
+            e.paused = false
+            if e.run(false, func() bool { _, ok := e.flow.loops[loop.head]; return ok }) { return }
+        }
+    }
+    e.dbg.OnStopOnStep(e.currentLine, e.currentCol)
+}
+
+func (e *Engine) StepIn(targetID *int) {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    if targetID != nil {
+        e.currentCol = targetID
+    } else {
+        if e.currentCol != nil {
+            v := *e.currentCol + 1
+            e.currentCol = &v
+        } else {
+            v := 1
+            e.currentCol = &v
+        }
+    }
+    e.dbg.OnStopOnStep(e.currentLine, e.currentCol)
+}
+
+func (e *Engine) StepOut() {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    if e.currentCol != nil {
+        v := *e.currentCol - 1
+        if v <= 0 { e.currentCol = nil } else { e.currentCol = &v }
+    }
+    e.dbg.OnStopOnStep(e.currentLine, e.currentCol)
+}
+
+// GotoTargets lists the places execution can jump to on line: the line
+// itself when it holds a statement. Target ids are line numbers.
+func (e *Engine) GotoTargets(line int) []protocol.GotoTarget {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    out := []protocol.GotoTarget{}
+    if e.verifyLine("", line) {
+        out = append(out, protocol.GotoTarget{ID: line, Label: "line " + itoa(line+1), Line: line})
+    }
+    return out
+}
+
+// Goto moves execution to the start of line without running anything in
+// between. It fails while a run started by Go is in progress; NotifyGoto
+// reports the jump once the client has its response.
+func (e *Engine) Goto(line int) error {
+    if !e.verifyLine("", line) { return fmt.Errorf("cannot jump to line %d", line) }
+    if e.Running() { return errors.New("cannot jump while the program is running") }
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    e.currentLine, e.currentCol = line, nil
+    e.instruction = e.starts[line]
+    e.flow.jump = -1
+    return nil
+}
+
+// NotifyGoto tells the client its stacks are stale and execution stopped
+// at the goto target.
+func (e *Engine) NotifyGoto() {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    e.dbg.OnInvalidated([]string{"stacks"})
+    e.dbg.OnStopOnGoto(e.currentLine, e.currentCol)
+}
+
+func (e *Engine) BuildStack(start, end int) (frames []protocol.StackFrame, count int) {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    line := e.getLine(e.currentLine)
+    words := e.lang.Words(e.currentLine, line)
+    words = append(words, Word{Name: "BOTTOM", Line: -1, Index: -1})
+    column := 0
+    if e.currentCol != nil { column = *e.currentCol }
+    frames = []protocol.StackFrame{}
+    for i := max(start, 0); i < min(end, len(words)); i++ {
+        src := e.frameSource()
+        frames = append(frames, protocol.StackFrame{ID: i, Name: words[i].Name + "(" + itoa(i) + ")", Source: &src, Line: e.currentLine, Column: column})
+    }
+    return frames, len(words)
+}
+
+func (e *Engine) GetBreakpointColumns(_path string, line int) []int {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    cols := []int{}
+    for _, w := range e.lang.Words(line, e.getLine(line)) {
+        if len(w.Name) > 8 { cols = append(cols, w.Index) }
+    }
+    return cols
+}
+
+func (e *Engine) GetBreakpointLines() []int {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    out := []int{}
+    for i := range e.sourceLines {
+        if e.verifyLine("", i) { out = append(out, i) }
+    }
+    return out
+}
+
+func (e *Engine) Disassemble(address, count int) []protocol.DisassembledInstruction {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    list := []protocol.DisassembledInstruction{}
+    for a := address; a < address+count; a++ {
+        if a >= 0 && a < len(e.instructions) {
+            w := e.instructions[a]
+            list = append(list, protocol.DisassembledInstruction{Address: a, Instruction: w.Name, Line: &w.Line})
+        } else {
+            list = append(list, protocol.DisassembledInstruction{Address: a, Instruction: "nop"})
+        }
+    }
+    return list
+}
+
+// Variables & breakpoints APIs
+func (e *Engine) GetLocalVariables() []protocol.Variable {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    return e.listScope(LocalsRef)
+}
+
+func (e *Engine) GetLocalVariable(name string) *protocol.NamedValue {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    if v, ok := e.lookup(name); ok { return &protocol.NamedValue{Name: name, Value: v} }
+    return nil
+}
+
+func (e *Engine) GetGlobalVariables() []protocol.Variable {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    return e.listScope(GlobalsRef)
+}
+
+func (e *Engine) SetExceptionsFilters(named *string, others bool) {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    e.namedException = named
+    e.otherExceptions = others
+}
+
+func (e *Engine) SetDataBreakpoint(address, access string) bool {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    if access == "readWrite" { access = "read write" }
+    if cur, ok := e.dataBps[address]; ok {
+        if cur != access { e.dataBps[address] = "read write" }
+    } else { e.dataBps[address] = access }
+    return true
+}
+func (e *Engine) ClearAllDataBreakpoints() {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    e.dataBps = map[string]string{}
+}
+
+func (e *Engine) SetInstructionBreakpoint(addr int) bool {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    e.instrBps[addr] = struct{}{}
+    return true
+}
+func (e *Engine) ClearInstructionBreakpoints() {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    e.instrBps = map[int]struct{}{}
+}
+
+// helpers
+func (e *Engine) verifyLine(_path string, line int) bool {
+    if line < 0 || line >= len(e.sourceLines) || e.flow.isMarker(line) { return false }
+    return strings.TrimSpace(e.sourceLines[line]) != ""
+}
+
+func (e *Engine) getLine(line int) string {
+    if line < 0 || line >= len(e.sourceLines) { return "" }
+    return e.sourceLines[line]
+}
+
+func (e *Engine) updateCurrentLine(reverse bool) bool {
+    if reverse {
+        if e.currentLine > 0 { e.currentLine-- } else { e.currentLine = 0; e.currentCol = nil; e.dbg.OnStopOnEntry(e.currentLine, e.currentCol); return true }
+    } else {
+        if next := e.flow.next(e.currentLine); next < len(e.sourceLines) { e.currentLine = next } else { e.currentCol = nil; return true }
+    }
+    return false
+}
+
+func (e *Engine) findNextStatement(reverse bool) bool {
+    for ln := e.currentLine; ; {
+        // line bp
+        if list, ok := e.bps[e.sourceKey()]; ok && !e.noDebug {
+            for i, bp := range list {
+                if bp.Line == ln {
+                    if !bp.Verified {
+                        // hitting an unverified breakpoint verifies it for good
+                        list[i].Verified, list[i].Message = true, ""
+                        e.dbg.OnBreakpoint("changed", list[i])
+                    }
+                    e.currentLine = ln
+                    e.dbg.OnStopOnBreakpoint(e.currentLine, e.currentCol)
+                    return true
+                }
+            }
+        }
+        // instr bp at line start/end
+        addr := 0
+        if reverse { addr = e.starts[ln] } else { addr = e.ends[ln]-1 }
+        if _, ok := e.instrBps[addr]; ok && !e.noDebug { e.currentLine = ln; e.dbg.OnStopOnInstructionBreakpoint(e.currentLine, e.currentCol); return true }
+
+        line := strings.TrimSpace(e.getLine(ln))
+        if line != "" { e.currentLine = ln; break }
+        if reverse { if ln <= 0 { break }; ln-- } else { ln++; if ln >= len(e.sourceLines) { break } }
+    }
+    return false
+}
+
+func (e *Engine) executeLine(ln int, reverse bool) bool {
+    if ln < 0 || ln >= len(e.starts) { return false }
+    // instruction breakpoints first
+    start := e.starts[ln]
+    end := e.ends[ln]
+    if reverse {
+        for e.instruction >= start {
+            e.instruction--
+            if _, ok := e.instrBps[e.instruction]; ok && !e.noDebug { e.dbg.OnStopOnInstructionBreakpoint(ln, e.currentCol); return true }
+        }
+    } else {
+        for e.instruction < end {
+            e.instruction++
+            if _, ok := e.instrBps[e.instruction]; ok && !e.noDebug { e.dbg.OnStopOnInstructionBreakpoint(ln, e.currentCol); return true }
+        }
+    }
+
+    if e.execute(e.sourceFile, ln, strings.TrimSpace(e.getLine(ln)), true) { return true }
+    if !reverse { e.enter(e.flow, ln) }
+    return false
+}
+
+// access applies a variable read or assignment and reports whether a data
+// breakpoint stopped. An assignment reads the variables of its expression
+// and then writes its target; the first access of a variable declares it and
+// never stops.
+func (e *Engine) access(file string, ln int, ef Effect, stops bool) bool {
+    accesses := [][2]string{{ef.Name, "read"}}
+    if ef.Kind == EffectAssign {
+        v, _, reads, err := e.evaluate(ef.Text)
+        accesses = accesses[:0]
+        for _, r := range reads { accesses = append(accesses, [2]string{r, "read"}) }
+        if err != nil {
+            e.dbg.OnOutput(Output{Category: "stderr", Text: "$" + ef.Name + ": " + err.Error(), File: file, Line: ln, Column: ef.Column})
+        } else {
+            if _, ok := e.variables[ef.Name]; ok { accesses = append(accesses, [2]string{ef.Name, "write"}) }
+            e.variables[ef.Name] = struct{}{}
+            e.assign(ef.Name, v)
+        }
+    }
+    if !stops { return false }
+    for _, a := range accesses {
+        if _, known := e.variables[a[0]]; !known { continue }
+        if flg, ok := e.dataBps[a[0]]; ok && strings.Contains(flg, a[1]) {
+            e.dbg.OnStopOnDataBreakpoint(ln, e.currentCol)
+            return true
+        }
+    }
+    return false
+}
+
+// execute applies the side-effects of one line of file. Stops are only
+// reported when stops is set, i.e. for the program itself but not for
+// included files.
+func (e *Engine) execute(file string, ln int, text string, stops bool) bool {
+    stops = stops && !e.noDebug
+
+    for _, ef := range e.lang.Effects(text) {
+        switch ef.Kind {
+        case EffectGlobal:
+            e.declareGlobal(ef.Name)
+        case EffectRead, EffectAssign:
+            if e.access(file, ln, ef, stops) { return true }
+        case EffectOutput:
+            e.emit(ef.Name, ef.Text, file, ln, ef.Column)
+        case EffectInclude:
+            e.include(file, ln, ef.Column, ef.Text)
+        case EffectException:
+            if !stops { continue }
+            if ef.Name != "" && e.namedException != nil && *e.namedException == ef.Name {
+                ex := ef.Name
+                e.dbg.OnStopOnException(ln, &ex, e.currentCol)
+                return true
+            }
+            if e.otherExceptions { e.dbg.OnStopOnException(ln, nil, e.currentCol); return true }
+        }
+    }
+    return false
+}
+
+// utils
+func splitLines(s string) []string {
+    sc := bufio.NewScanner(strings.NewReader(s))
+    lines := []string{}
+    for sc.Scan() { lines = append(lines, sc.Text()) }
+    // append last empty line? not needed for our semantics
+    return lines
+}
+
+
+func abs(p string) string { a, _ := filepath.Abs(p); return a }
+func basename(p string) string { return filepath.Base(p) }
+func min(a, b int) int { if a < b { return a } ; return b }
+func itoa(i int) string { return strconvItoa(i) }
+
+// tiny itoa without importing strconv everywhere
+func strconvItoa(i int) string {
+    // simple base-10 conversion
+    if i == 0 { return "0" }
+    neg := false
+    if i < 0 { neg = true; i = -i }
+    var buf [20]byte
+    bp := len(buf)
+    for i > 0 { bp--; buf[bp] = byte('0' + (i % 10)); i /= 10 }
+    if neg { bp--; buf[bp] = '-' }
+    return string(buf[bp:])
+}
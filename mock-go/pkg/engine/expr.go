@@ -0,0 +1,301 @@
+package engine
+
+import (
+    "errors"
+    "fmt"
+    "math"
+    "regexp"
+    "strings"
+)
+
+// Expressions of the mock language, from lowest to highest precedence:
+//
+//    ||  &&  == != < <= > >=  + -  * / %  unary - ! not
+//
+// Operands are numbers, "strings", true/false/null, array and object
+// literals, variable references with selectors ($obj.count, $arr[1]),
+// read() and parenthesized expressions. Expressions are evaluated while
+// they are parsed; parsing stops at the first text that cannot continue
+// the expression, so an assignment may be followed by other statements on
+// the same line.
+// Without an engine the parser only measures the expression.
+// A pure parser refuses read() instead of consuming program input.
+type exprParser struct {
+    e     *Engine
+    s     string
+    pos   int
+    reads []string
+    err   error
+    pure  bool
+}
+
+var (
+    refRe    = regexp.MustCompile(`^\$[a-zA-Z][a-zA-Z0-9_]*(?:\.[a-zA-Z0-9_]+|\[[^\]]+\])*`)
+    numberRe = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?`)
+    keywordRe = regexp.MustCompile(`^((true|false|null)\b|read\(\))`)
+
+    errImpureRead = errors.New("read() cannot be evaluated")
+)
+
+// ExpressionLength returns the length of the longest expression at the
+// start of s, or 0 when s does not start with one. Languages use it to find
+// where the right-hand side of an assignment ends.
+func ExpressionLength(s string) int {
+    p := &exprParser{s: s}
+    if _, ok := p.or(); !ok { return 0 }
+    return p.pos
+}
+
+// evaluate parses the longest expression at the start of s. It returns the
+// value, the length of text consumed (0 when s does not start with an
+// expression), the root names of the variables read, and an evaluation
+// error such as a division by zero.
+func (e *Engine) evaluate(s string) (v any, n int, reads []string, err error) {
+    p := &exprParser{e: e, s: s}
+    v, ok := p.or()
+    if !ok { return nil, 0, p.reads, nil }
+    return v, p.pos, p.reads, p.err
+}
+
+func (p *exprParser) space() {
+    for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') { p.pos++ }
+}
+
+// op consumes the first of ops found after optional spaces.
+func (p *exprParser) op(ops ...string) (string, bool) {
+    save := p.pos
+    p.space()
+    for _, o := range ops {
+        if strings.HasPrefix(p.s[p.pos:], o) {
+            p.pos += len(o)
+            return o, true
+        }
+    }
+    p.pos = save
+    return "", false
+}
+
+// binary parses operand (op operand)*, leaving an operator without a right
+// operand unconsumed.
+func (p *exprParser) binary(next func() (any, bool), ops ...string) (any, bool) {
+    left, ok := next()
+    if !ok { return nil, false }
+    for {
+        save := p.pos
+        o, found := p.op(ops...)
+        if !found { return left, true }
+        right, ok := next()
+        if !ok { p.pos = save; return left, true }
+        left = p.apply(o, left, right)
+    }
+}
+
+func (p *exprParser) or() (any, bool)  { return p.binary(p.and, "||") }
+func (p *exprParser) and() (any, bool) { return p.binary(p.cmp, "&&") }
+func (p *exprParser) cmp() (any, bool) { return p.binary(p.add, "==", "!=", "<=", ">=", "<", ">") }
+func (p *exprParser) add() (any, bool) { return p.binary(p.mul, "+", "-") }
+func (p *exprParser) mul() (any, bool) { return p.binary(p.unary, "*", "/", "%") }
+
+func (p *exprParser) unary() (any, bool) {
+    save := p.pos
+    p.space()
+    neg := ""
+    switch {
+    case strings.HasPrefix(p.s[p.pos:], "-"): neg = "-"; p.pos++
+    case strings.HasPrefix(p.s[p.pos:], "!") && !strings.HasPrefix(p.s[p.pos:], "!="): neg = "!"; p.pos++
+    case strings.HasPrefix(p.s[p.pos:], "not "): neg = "!"; p.pos += 4
+    }
+    if neg == "" { p.pos = save; return p.primary() }
+    v, ok := p.unary()
+    if !ok { p.pos = save; return nil, false }
+    if neg == "!" { return !isTruthy(v), true }
+    return p.apply("-", 0, v), true
+}
+
+func (p *exprParser) primary() (any, bool) {
+    save := p.pos
+    p.space()
+    rest := p.s[p.pos:]
+    if rest == "" { p.pos = save; return nil, false }
+    switch c := rest[0]; {
+    case c == '(':
+        p.pos++
+        v, ok := p.or()
+        if _, closed := p.op(")"); !ok || !closed { p.pos = save; return nil, false }
+        return v, true
+    case c == '"':
+        end := strings.IndexByte(rest[1:], '"')
+        if end < 0 { p.pos = save; return nil, false }
+        p.pos += end + 2
+        return rest[1 : end+1], true
+    case c == '[' || c == '{':
+        end := matchBracket(rest)
+        if end < 0 { p.pos = save; return nil, false }
+        p.pos += end
+        return p.literal(rest[:end]), true
+    case c == '$':
+        ref := refRe.FindString(rest)
+        if ref == "" { p.pos = save; return nil, false }
+        p.pos += len(ref)
+        if p.e == nil { return nil, true }
+        path, err := p.e.lvalue(ref)
+        if err != nil { p.err = err; return nil, true }
+        p.reads = append(p.reads, path.names[0])
+        v, _ := p.e.valueAt(path)
+        return v, true
+    }
+    if m := numberRe.FindString(rest); m != "" {
+        p.pos += len(m)
+        return parseToken(m), true
+    }
+    switch keywordRe.FindString(rest) {
+    case "true": p.pos += 4; return true, true
+    case "false": p.pos += 5; return false, true
+    case "null": p.pos += 4; return nil, true
+    case "read()":
+        p.pos += 6
+        if p.e == nil { return nil, true }
+        if p.pure { p.err = errImpureRead; return nil, true }
+        return p.e.readInput(), true
+    }
+    p.pos = save
+    return nil, false
+}
+
+// literal builds an array or object literal whose elements are
+// expressions. Braced text that is not a `{key: value}` object yields the
+// demo object, like parseToken.
+func (p *exprParser) literal(t string) any {
+    parts := splitTop(t[1 : len(t)-1])
+    if t[0] == '{' && len(parts) == 0 { return parseToken(t) }
+    fields := make([]map[string]any, 0, len(parts))
+    for i, part := range parts {
+        name, src := itoa(i), part
+        if t[0] == '{' {
+            k, v, ok := strings.Cut(part, ":")
+            k = strings.TrimSpace(k)
+            if !ok || !identRe.MatchString(k) { return parseToken(t) }
+            name, src = k, strings.TrimSpace(v)
+        }
+        fields = append(fields, map[string]any{"name": name, "value": p.element(src)})
+    }
+    return fields
+}
+
+// element evaluates one element of a literal; elements that are not a
+// single expression are parsed as literals. Elements of a pure parser's
+// literal are pure too.
+func (p *exprParser) element(src string) any {
+    if p.e == nil { return nil }
+    q := &exprParser{e: p.e, s: src, pure: p.pure}
+    v, ok := q.or()
+    if q.err == errImpureRead { p.err = q.err; return nil }
+    if !ok || q.pos != len(src) || q.err != nil { return parseToken(src) }
+    p.reads = append(p.reads, q.reads...)
+    return v
+}
+
+// matchBracket returns the length of the bracketed literal at the start of
+// s, or -1 when it is not closed.
+func matchBracket(s string) int {
+    depth, quoted := 0, false
+    for i := 0; i < len(s); i++ {
+        switch c := s[i]; {
+        case c == '"': quoted = !quoted
+        case quoted:
+        case c == '[' || c == '{': depth++
+        case c == ']' || c == '}':
+            depth--
+            if depth == 0 { return i + 1 }
+        }
+    }
+    return -1
+}
+
+// apply evaluates one binary operator. The first error is kept and the
+// expression evaluates to null from there on.
+func (p *exprParser) apply(op string, a, b any) any {
+    if p.err != nil { return nil }
+    switch op {
+    case "||": return isTruthy(a) || isTruthy(b)
+    case "&&": return isTruthy(a) && isTruthy(b)
+    case "==", "!=", "<", "<=", ">", ">=": return compare(a, op, b)
+    }
+    v, err := arith(op, a, b)
+    if err != nil { p.err = err }
+    return v
+}
+
+// arith applies an arithmetic operator. Integers stay integers (division
+// truncates); + concatenates when either side is a string.
+func arith(op string, a, b any) (any, error) {
+    _, as := a.(string)
+    _, bs := b.(string)
+    if op == "+" && (as || bs) { return formatValue(a) + formatValue(b), nil }
+    x, xok := number(a)
+    y, yok := number(b)
+    if !xok || !yok { return nil, fmt.Errorf("cannot apply %s to %s and %s", op, typeOf(a), typeOf(b)) }
+    i, iok := a.(int)
+    j, jok := b.(int)
+    if iok && jok {
+        switch op {
+        case "+": return i + j, nil
+        case "-": return i - j, nil
+        case "*": return i * j, nil
+        }
+        if j == 0 { return nil, fmt.Errorf("division by zero") }
+        if op == "/" { return i / j, nil }
+        return i % j, nil
+    }
+    var r float64
+    switch op {
+    case "+": r = x + y
+    case "-": r = x - y
+    case "*": r = x * y
+    case "/": r = x / y
+    case "%": r = math.Mod(x, y)
+    }
+    if math.IsNaN(r) || math.IsInf(r, 0) { return nil, fmt.Errorf("%s of %s and %s is not a number", op, formatValue(a), formatValue(b)) }
+    return r, nil
+}
+
+func isTruthy(v any) bool {
+    switch t := v.(type) {
+    case nil: return false
+    case bool: return t
+    case int: return t != 0
+    case float64: return t != 0
+    case string: return t != ""
+    case []map[string]any: return len(t) > 0
+    }
+    return true
+}
+
+// compare applies op numerically when both sides are numbers and to the
+// formatted values otherwise.
+func compare(a any, op string, b any) bool {
+    x, xok := number(a)
+    y, yok := number(b)
+    c := 0
+    if xok && yok {
+        if x < y { c = -1 } else if x > y { c = 1 }
+    } else {
+        c = strings.Compare(formatValue(a), formatValue(b))
+    }
+    switch op {
+    case "==": return c == 0
+    case "!=": return c != 0
+    case "<": return c < 0
+    case "<=": return c <= 0
+    case ">": return c > 0
+    }
+    return c >= 0
+}
+
+func number(v any) (float64, bool) {
+    switch t := v.(type) {
+    case int: return float64(t), true
+    case float64: return t, true
+    }
+    return 0, false
+}
@@ -0,0 +1,22 @@
+package engine
+
+import (
+    "testing"
+    "time"
+)
+
+func TestEvaluateNestedRead(t *testing.T) {
+    e := New(NopDebugger{})
+    e.Input("queued")
+    for _, expr := range []string{"read()", "[read()]", "{a: read()}", "[1, [read()]]"} {
+        done := make(chan error, 1)
+        go func() { _, err := e.Evaluate(expr); done <- err }()
+        select {
+        case err := <-done:
+            if err != errImpureRead { t.Errorf("Evaluate(%q) = %v, want %v", expr, err, errImpureRead) }
+        case <-time.After(time.Second):
+            t.Fatalf("Evaluate(%q) blocked", expr)
+        }
+    }
+    if got := e.readInput(); got != "queued" { t.Errorf("input was consumed: read %q", got) }
+}
@@ -0,0 +1,50 @@
+package engine
+
+import "strings"
+
+// Input queues text for read(). Each line of text satisfies one read() call.
+func (e *Engine) Input(text string) {
+    e.inputMu.Lock()
+    defer e.inputMu.Unlock()
+    text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+    e.input = append(e.input, strings.Split(text, "\n")...)
+    e.inputCond.Broadcast()
+}
+
+// CloseInput marks the end of program input; pending and future reads
+// return an empty string once the queue is drained.
+func (e *Engine) CloseInput() {
+    e.inputMu.Lock()
+    defer e.inputMu.Unlock()
+    e.inputClosed = true
+    e.inputCond.Broadcast()
+}
+
+// ResetInput drops queued input and reopens the stream for a new program.
+func (e *Engine) ResetInput() {
+    e.inputMu.Lock()
+    defer e.inputMu.Unlock()
+    e.input = nil
+    e.inputClosed = false
+}
+
+// readInput takes the next input line for a run, waiting for one if
+// needed. The program state can be inspected while it waits: execMu is
+// released until the line has arrived.
+func (e *Engine) readInput() string {
+    e.inputMu.Lock()
+    if len(e.input) == 0 && !e.inputClosed {
+        e.blocked()
+        e.execMu.Unlock()
+        for len(e.input) == 0 && !e.inputClosed && !e.halted() { e.inputCond.Wait() }
+        // take execMu without holding inputMu, which Input needs
+        e.inputMu.Unlock()
+        e.execMu.Lock()
+        e.inputMu.Lock()
+    }
+    defer e.inputMu.Unlock()
+    if len(e.input) == 0 { return "" }
+    line := e.input[0]
+    e.input = e.input[1:]
+    return line
+}
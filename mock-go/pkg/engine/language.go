@@ -0,0 +1,122 @@
+package engine
+
+import (
+    "regexp"
+    "strings"
+)
+
+// Language is the front end of the engine: it splits a line of program
+// text into words (the instructions shown by stack traces and disassembly)
+// and into the side-effects the engine applies when the line executes.
+// Expressions, scoping, breakpoints and stepping stay with the engine, so a
+// language only decides what a line says, not how it runs.
+type Language interface {
+    // Words splits line ln into instructions.
+    Words(ln int, text string) []Word
+    // Effects lists the side-effects of a trimmed line in execution order.
+    Effects(text string) []Effect
+    // Block classifies a trimmed line for control flow: "if", "while" or
+    // "repeat" with the condition or count, "else", "end", or "" for
+    // ordinary lines.
+    Block(text string) (kind, cond string)
+}
+
+// SetLanguage replaces the front end (Markdown by default). It takes effect
+// with the next program load.
+func (e *Engine) SetLanguage(l Language) { e.lang = l }
+
+// EffectKind says what an Effect does.
+type EffectKind int
+
+const (
+    // EffectGlobal declares Name a global variable.
+    EffectGlobal EffectKind = iota
+    // EffectRead reads the variable Name.
+    EffectRead
+    // EffectAssign evaluates the expression Text and writes it to Name.
+    EffectAssign
+    // EffectOutput calls the output function Name (log, prio, out, err,
+    // telemetry, group or endgroup) with the payload Text.
+    EffectOutput
+    // EffectInclude runs the file Text.
+    EffectInclude
+    // EffectException throws the exception Name, or an unnamed one.
+    EffectException
+)
+
+// Effect is one side-effect of a line. Column is where it starts.
+type Effect struct {
+    Kind   EffectKind
+    Column int
+    Name   string
+    Text   string
+}
+
+// Markdown is the default language: the mock debug markdown, where words
+// are instructions, `$name` and `$name=<expression>` access variables and
+// log(...), include(...) and exception(...) calls have side-effects.
+type Markdown struct{}
+
+var (
+    wordRe    = regexp.MustCompile(`[a-zA-Z]+`)
+    varRe     = regexp.MustCompile(`\$([a-zA-Z][a-zA-Z0-9_]*)`)
+    logRe     = regexp.MustCompile(`(endgroup|group|telemetry|log|prio|out|err)\(([^\)]*)\)`)
+    excName   = regexp.MustCompile(`exception\((.*)\)`)
+    includeRe = regexp.MustCompile(`include\(([^\)]*)\)`)
+    excToken  = regexp.MustCompile(`\bexception\b`)
+    headerRe  = regexp.MustCompile(`^(if|while|repeat)\s+(.+)$`)
+    // globalRe marks a variable as global: `global $name` or `global $name=value`.
+    globalRe = regexp.MustCompile(`\bglobal\s+\$([a-zA-Z][a-zA-Z0-9_]*)`)
+)
+
+func (Markdown) Words(ln int, text string) []Word {
+    out := []Word{}
+    for _, m := range wordRe.FindAllStringSubmatchIndex(text, -1) {
+        out = append(out, Word{Name: text[m[0]:m[1]], Line: ln, Index: m[0]})
+    }
+    return out
+}
+
+// Effects applies global declarations first, then variable accesses, output,
+// includes and finally exceptions.
+func (Markdown) Effects(text string) []Effect {
+    out := []Effect{}
+    for _, m := range globalRe.FindAllStringSubmatchIndex(text, -1) {
+        out = append(out, Effect{Kind: EffectGlobal, Column: m[0], Name: text[m[2]:m[3]]})
+    }
+
+    // `$x=<expr>` assigns x; any other `$x` reads it
+    for i := 0; i < len(text); {
+        m := varRe.FindStringSubmatchIndex(text[i:])
+        if m == nil { break }
+        col, name, at := i+m[0], text[i+m[2]:i+m[3]], i+m[1]
+        i = at
+        if strings.HasPrefix(text[at:], "=") && !strings.HasPrefix(text[at:], "==") {
+            if n := ExpressionLength(text[at+1:]); n > 0 {
+                out = append(out, Effect{Kind: EffectAssign, Column: col, Name: name, Text: text[at+1 : at+1+n]})
+                i = at + 1 + n
+                continue
+            }
+        }
+        out = append(out, Effect{Kind: EffectRead, Column: col, Name: name})
+    }
+
+    for _, m := range logRe.FindAllStringSubmatchIndex(text, -1) {
+        out = append(out, Effect{Kind: EffectOutput, Column: m[0], Name: text[m[2]:m[3]], Text: text[m[4]:m[5]]})
+    }
+    for _, m := range includeRe.FindAllStringSubmatchIndex(text, -1) {
+        out = append(out, Effect{Kind: EffectInclude, Column: m[0], Text: text[m[2]:m[3]]})
+    }
+    if m := excName.FindStringSubmatchIndex(text); m != nil {
+        out = append(out, Effect{Kind: EffectException, Column: m[0], Name: strings.TrimSpace(text[m[2]:m[3]])})
+    } else if m := excToken.FindStringIndex(text); m != nil {
+        out = append(out, Effect{Kind: EffectException, Column: m[0]})
+    }
+    return out
+}
+
+func (Markdown) Block(text string) (kind, cond string) {
+    if m := headerRe.FindStringSubmatch(text); m != nil { return m[1], strings.TrimSpace(m[2]) }
+    if text == "else" || text == "end" { return text, "" }
+    return "", ""
+}
@@ -0,0 +1,90 @@
+package engine
+
+import (
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+
+    "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/protocol"
+)
+
+// LaunchConfig mirrors the DAP launch arguments a program runs with.
+type LaunchConfig struct {
+    Args    []string
+    Env     map[string]string
+    Cwd     string
+    NoDebug bool
+}
+
+const maxIncludeDepth = 16
+
+var envNameRe = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// Configure starts a launch with the given arguments: variables of an
+// earlier launch are dropped, the globals $ARGV and $ENV_<NAME> hold the
+// program arguments and environment, and relative paths resolve against
+// Cwd. With NoDebug set the program runs without ever stopping. Stop any
+// run of the earlier program first.
+func (e *Engine) Configure(cfg LaunchConfig) {
+    e.resetVariables()
+    e.cwd = cfg.Cwd
+    e.noDebug = cfg.NoDebug
+
+    argv := []map[string]any{}
+    for i, a := range cfg.Args { argv = append(argv, map[string]any{"name": itoa(i), "value": a}) }
+    e.seed("ARGV", argv)
+
+    names := make([]string, 0, len(cfg.Env))
+    for k := range cfg.Env { names = append(names, k) }
+    sort.Strings(names)
+    for _, k := range names { e.seed("ENV_"+envNameRe.ReplaceAllString(k, "_"), cfg.Env[k]) }
+}
+
+// NoDebug reports whether the current launch ignores breakpoints.
+func (e *Engine) NoDebug() bool { return e.noDebug }
+
+// ResolvePath resolves p against the launch cwd, falling back to the
+// directory of the loaded program.
+func (e *Engine) ResolvePath(p string) string {
+    if p == "" || filepath.IsAbs(p) { return p }
+    base := e.cwd
+    if base == "" && e.sourceFile != "" { base = filepath.Dir(e.sourceFile) }
+    return abs(filepath.Join(base, p))
+}
+
+func (e *Engine) seed(name string, value any) {
+    e.variables[name] = struct{}{}
+    e.globals[name] = value
+}
+
+// include runs the side-effects of another file (assignments, output and
+// nested includes) in a frame of its own, without stepping into it.
+func (e *Engine) include(from string, ln, col int, target string) {
+    target = strings.Trim(strings.TrimSpace(target), "\"")
+    if e.includeDepth >= maxIncludeDepth {
+        e.dbg.OnOutput(Output{Category: "stderr", Text: "include nested too deeply: " + target, File: from, Line: ln, Column: col})
+        return
+    }
+    path := e.ResolvePath(target)
+    data, err := os.ReadFile(path)
+    if err != nil {
+        e.dbg.OnOutput(Output{Category: "stderr", Text: "cannot include " + target, File: from, Line: ln, Column: col})
+        return
+    }
+    e.track(path, protocol.Source{Name: basename(path), Path: path}, string(data))
+    e.includeDepth++
+    e.pushFrame()
+    defer func() { e.popFrame(); e.includeDepth-- }()
+    lines := splitLines(string(data))
+    f := newFlow(e.lang, lines)
+    for i, steps := 0, 0; i < len(lines); i = f.next(i) {
+        if steps++; steps > maxIncludeSteps {
+            e.dbg.OnOutput(Output{Category: "stderr", Text: "include ran too long: " + target, File: from, Line: ln, Column: col})
+            return
+        }
+        e.execute(path, i, strings.TrimSpace(lines[i]), false)
+        e.enter(f, i)
+    }
+}
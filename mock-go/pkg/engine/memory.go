@@ -0,0 +1,136 @@
+package engine
+
+import (
+    "encoding/binary"
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+// Every primitive variable owns a memory region of memRegion bytes holding
+// its byte representation: booleans take one byte, integers and floats
+// eight (little endian), strings their UTF-8 bytes. Bytes past the end of a
+// representation, and regions nobody owns, are unreadable.
+const memRegion = 0x1000
+
+// memOf returns the base address of path's region, allocating one if needed.
+func (e *Engine) memOf(path varPath) int {
+    k := path.key()
+    if addr, ok := e.mems[k]; ok { return addr }
+    addr := (len(e.mems) + 1) * memRegion
+    e.mems[k] = addr
+    e.memPaths[addr] = path
+    return addr
+}
+
+// memoryReference formats an address the way DAP clients expect.
+func memoryReference(addr int) string { return fmt.Sprintf("0x%08x", addr) }
+
+func parseAddress(ref string) (int, error) {
+    ref = strings.TrimSpace(ref)
+    var v int64
+    var err error
+    if strings.HasPrefix(ref, "0x") || strings.HasPrefix(ref, "0X") {
+        v, err = strconv.ParseInt(ref[2:], 16, 64)
+    } else {
+        v, err = strconv.ParseInt(ref, 10, 64)
+    }
+    if err != nil || v < 0 { return 0, fmt.Errorf("invalid memoryReference: %s", ref) }
+    return int(v), nil
+}
+
+// ReadMemory reads up to count bytes at memoryReference+offset. Reading stops
+// at the first unreadable byte; the remainder is reported as unreadable.
+// Negative offsets and counts are rejected.
+func (e *Engine) ReadMemory(ref string, offset, count int) (address string, data []byte, unreadable int, err error) {
+    if offset < 0 { return "", nil, 0, fmt.Errorf("offset must not be negative: %d", offset) }
+    if count < 0 { return "", nil, 0, fmt.Errorf("count must not be negative: %d", count) }
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    base, err := parseAddress(ref)
+    if err != nil { return "", nil, 0, err }
+    addr := base + offset
+    if addr < 0 { return "", nil, 0, fmt.Errorf("address out of range") }
+    for len(data) < count {
+        b, ok := e.byteAt(addr + len(data))
+        if !ok { break }
+        data = append(data, b)
+    }
+    return memoryReference(addr), data, count - len(data), nil
+}
+
+// WriteMemory writes data at memoryReference+offset into the variable owning
+// that region and decodes the bytes back into its value. Strings may grow up
+// to the region size; other types are fixed-width. Without allowPartial a
+// write that does not fit fails as a whole.
+func (e *Engine) WriteMemory(ref string, offset int, data []byte, allowPartial bool) (int, error) {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    base, err := parseAddress(ref)
+    if err != nil { return 0, err }
+    addr := base + offset
+    region := addr - addr%memRegion
+    path, ok := e.memPaths[region]
+    if !ok || addr < 0 { return 0, fmt.Errorf("memory at %s is not writable", memoryReference(addr)) }
+    cur, ok := e.valueAt(path)
+    if !ok { return 0, fmt.Errorf("memory at %s is not writable", memoryReference(addr)) }
+    buf, ok := encodeValue(cur)
+    if !ok { return 0, fmt.Errorf("memory at %s is not writable", memoryReference(addr)) }
+    limit := len(buf)
+    if _, isString := cur.(string); isString { limit = memRegion }
+    at := addr - region
+    if at > len(buf) { return 0, fmt.Errorf("memory at %s is not writable", memoryReference(addr)) }
+    n := min(len(data), limit-at)
+    if n < len(data) && !allowPartial { return 0, fmt.Errorf("write of %d bytes does not fit at %s", len(data), memoryReference(addr)) }
+    if at+n > len(buf) { buf = append(buf, make([]byte, at+n-len(buf))...) }
+    copy(buf[at:], data[:n])
+    v, ok := decodeValue(cur, buf)
+    if !ok { return 0, fmt.Errorf("bytes at %s do not form a valid %s", memoryReference(addr), typeOf(cur)) }
+    e.put(path, v)
+    return n, nil
+}
+
+func (e *Engine) byteAt(addr int) (byte, bool) {
+    region := addr - addr%memRegion
+    path, ok := e.memPaths[region]
+    if !ok { return 0, false }
+    v, ok := e.valueAt(path)
+    if !ok { return 0, false }
+    buf, ok := encodeValue(v)
+    if !ok || addr-region >= len(buf) { return 0, false }
+    return buf[addr-region], true
+}
+
+// encodeValue returns the byte representation of a primitive value.
+func encodeValue(v any) ([]byte, bool) {
+    switch t := v.(type) {
+    case bool:
+        if t { return []byte{1}, true }
+        return []byte{0}, true
+    case int:
+        return binary.LittleEndian.AppendUint64(nil, uint64(int64(t))), true
+    case float64:
+        return binary.LittleEndian.AppendUint64(nil, math.Float64bits(t)), true
+    case string:
+        b := []byte(t)
+        if len(b) > memRegion { b = b[:memRegion] }
+        return b, true
+    }
+    return nil, false
+}
+
+// decodeValue turns bytes back into a value of cur's type; NaN and
+// infinities are rejected since they cannot be sent as JSON.
+func decodeValue(cur any, b []byte) (any, bool) {
+    switch cur.(type) {
+    case bool:
+        return b[0] != 0, true
+    case int:
+        return int(int64(binary.LittleEndian.Uint64(b))), true
+    case float64:
+        f := math.Float64frombits(binary.LittleEndian.Uint64(b))
+        return f, !math.IsNaN(f) && !math.IsInf(f, 0)
+    }
+    return string(b), true
+}
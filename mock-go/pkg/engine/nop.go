@@ -0,0 +1,27 @@
+package engine
+
+import "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/protocol"
+
+// NopDebugger ignores every callback. Embed it to implement only the
+// Debugger methods you care about.
+type NopDebugger struct{}
+
+func (NopDebugger) OnStopOnEntry(line int, column *int)                        {}
+func (NopDebugger) OnStopOnStep(line int, column *int)                         {}
+func (NopDebugger) OnStopOnBreakpoint(line int, column *int)                   {}
+func (NopDebugger) OnStopOnException(line int, exception *string, column *int) {}
+func (NopDebugger) OnStopOnDataBreakpoint(line int, column *int)               {}
+func (NopDebugger) OnStopOnInstructionBreakpoint(line int, column *int)        {}
+func (NopDebugger) OnStopOnPause(line int, column *int)                        {}
+func (NopDebugger) OnStopOnGoto(line int, column *int)                         {}
+func (NopDebugger) OnBreakpoint(reason string, bp Breakpoint)                  {}
+func (NopDebugger) OnOutput(o Output)                                          {}
+func (NopDebugger) OnLoadedSource(reason string, source protocol.Source)       {}
+func (NopDebugger) OnModule(reason string, module protocol.Module)             {}
+func (NopDebugger) OnInvalidated(areas []string)                               {}
+func (NopDebugger) OnProgressStart(id, title string, cancellable bool)         {}
+func (NopDebugger) OnProgressUpdate(id string, percentage int, message string) {}
+func (NopDebugger) OnProgressEnd(id, message string)                           {}
+func (NopDebugger) OnEnd()                                                     {}
+
+var _ Debugger = NopDebugger{}
@@ -0,0 +1,14 @@
+package engine
+
+// Option configures an Engine created by New.
+type Option func(*Engine)
+
+// WithLanguage replaces the Markdown front end.
+func WithLanguage(l Language) Option { return func(e *Engine) { e.lang = l } }
+
+// WithRelocateBreakpoints moves breakpoints on non-executable lines to the
+// next executable line.
+func WithRelocateBreakpoints(on bool) Option { return func(e *Engine) { e.relocate = on } }
+
+// WithLaunchConfig applies launch arguments as Configure does.
+func WithLaunchConfig(cfg LaunchConfig) Option { return func(e *Engine) { e.Configure(cfg) } }
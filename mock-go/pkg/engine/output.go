@@ -0,0 +1,117 @@
+package engine
+
+import (
+    "fmt"
+    "regexp"
+    "strings"
+)
+
+// Output is one piece of program output. Group is "start" or "end" for the
+// output of group(name) and endgroup(); VariablesReference makes a
+// structured value printed to the console expandable. Data carries the
+// payload of telemetry output.
+type Output struct {
+    Category           string
+    Text               string
+    File               string
+    Line               int
+    Column             int
+    Group              string
+    VariablesReference int
+    Data               map[string]any
+}
+
+// categories maps the output functions of the mock language onto DAP
+// output categories.
+var categories = map[string]string{
+    "log":       "console",
+    "prio":      "important",
+    "out":       "stdout",
+    "err":       "stderr",
+    "telemetry": "telemetry",
+}
+
+// Body renders the output in its wire form.
+func (o Output) Body() map[string]any {
+    m := map[string]any{"category": o.Category, "text": o.Text, "file": o.File, "line": o.Line, "column": o.Column}
+    if o.Group != "" { m["group"] = o.Group }
+    if o.VariablesReference > 0 { m["variablesReference"] = o.VariablesReference }
+    if o.Data != nil { m["data"] = o.Data }
+    return m
+}
+
+// interpRe matches variable references inside output payloads, optionally
+// followed by field selectors: `$x`, `$obj.count`, `$arr[2]`.
+var interpRe = regexp.MustCompile(`\$[a-zA-Z][a-zA-Z0-9_]*(?:\.[a-zA-Z0-9_]+|\[[^\]]+\])*`)
+
+// interpolate replaces the variable references in text by their values.
+// Unknown references stay verbatim. The reference of the first structured
+// value is returned so the client can expand it.
+func (e *Engine) interpolate(text string) (string, int) {
+    ref := 0
+    out := interpRe.ReplaceAllStringFunc(text, func(expr string) string {
+        path, err := e.lvalue(expr)
+        if err != nil { return expr }
+        v, ok := e.valueAt(path)
+        if !ok { return expr }
+        if _, structured := v.([]map[string]any); structured && ref == 0 { ref = e.refOf(path) }
+        return formatValue(v)
+    })
+    return out, ref
+}
+
+// emit sends the output of one output function call at ln:col of file.
+func (e *Engine) emit(fn, payload, file string, ln, col int) {
+    o := Output{Category: categories[fn], File: file, Line: ln, Column: col}
+    if o.Category == "" { o.Category = "console" }
+    switch fn {
+    case "group":
+        o.Category, o.Group, o.Text = "console", "start", strings.TrimSpace(payload)
+        e.groups++
+    case "endgroup":
+        if e.groups == 0 { return }
+        o.Category, o.Group = "console", "end"
+        e.groups--
+    case "telemetry":
+        // telemetry(event, {key: value}): the event name is the text and
+        // values may be variable references
+        parts := splitTop(payload)
+        if len(parts) == 0 { return }
+        o.Text, _ = e.interpolate(unquote(parts[0]))
+        o.Data = map[string]any{}
+        if len(parts) > 1 {
+            if fields, ok := parseObject(strings.Join(parts[1:], ", ")); ok { o.Data, _ = e.plain(fields).(map[string]any) }
+        }
+    default:
+        o.Text, o.VariablesReference = e.interpolate(payload)
+    }
+    e.dbg.OnOutput(o)
+}
+
+// plain converts a literal into JSON objects and arrays for payloads that
+// are not browsed as variables, resolving variable references on the way.
+func (e *Engine) plain(v any) any {
+    if s, ok := v.(string); ok && interpRe.FindString(s) == s && s != "" {
+        if path, err := e.lvalue(s); err == nil {
+            if val, ok := e.valueAt(path); ok { v = val }
+        }
+    }
+    fields, ok := v.([]map[string]any)
+    if !ok { return v }
+    list := make([]any, 0, len(fields))
+    m := make(map[string]any, len(fields))
+    for i, f := range fields {
+        name := fmt.Sprint(f["name"])
+        if list != nil && name == itoa(i) { list = append(list, e.plain(f["value"])) } else { list = nil }
+        m[name] = e.plain(f["value"])
+    }
+    if list != nil && len(fields) > 0 { return list }
+    return m
+}
+
+// closeGroups ends the groups still open when the program ends.
+func (e *Engine) closeGroups() {
+    for ; e.groups > 0; e.groups-- {
+        e.dbg.OnOutput(Output{Category: "console", Group: "end", File: e.sourceFile, Line: e.currentLine})
+    }
+}
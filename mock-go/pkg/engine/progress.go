@@ -0,0 +1,92 @@
+package engine
+
+import "sync"
+
+// Programs of at least loadProgressLines lines report progress while they
+// are tokenized; runs report progress once they have executed
+// runProgressSteps lines without stopping, and again every as many lines.
+const (
+    loadProgressLines = 5000
+    runProgressSteps  = 1000
+)
+
+// progress tracks the long operation currently reported to the client.
+// Cancel is called from the request loop while the run goroutine polls it.
+type progress struct {
+    mu        sync.Mutex
+    nextID    int
+    id        string
+    cancelled bool
+}
+
+// start allocates a progress id of the given kind and makes it current.
+func (p *progress) start(kind string) string {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    p.nextID++
+    p.id = kind + "-" + itoa(p.nextID)
+    p.cancelled = false
+    return p.id
+}
+
+func (p *progress) end() {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    p.id, p.cancelled = "", false
+}
+
+func (p *progress) isCancelled() bool {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    return p.cancelled
+}
+
+// Cancel asks the operation reporting progressID to stop. An empty id
+// cancels whatever is running. It reports whether there was anything to
+// cancel; a cancelled run stops as if paused.
+func (e *Engine) Cancel(progressID string) bool {
+    e.progress.mu.Lock()
+    defer e.progress.mu.Unlock()
+    if e.progress.id == "" || (progressID != "" && progressID != e.progress.id) { return false }
+    e.progress.cancelled = true
+    return true
+}
+
+// percent is how far ln is through the program.
+func (e *Engine) percent(ln int) int {
+    if len(e.sourceLines) == 0 { return 100 }
+    return (ln + 1) * 100 / len(e.sourceLines)
+}
+
+// runMeter counts the lines a run executes and reports progress once the
+// run gets long.
+type runMeter struct {
+    e         *Engine
+    id        string
+    steps     int
+    cancelled bool
+}
+
+// step counts one executed line and reports whether the run was cancelled.
+func (m *runMeter) step() bool {
+    m.steps++
+    if m.steps%runProgressSteps == 0 {
+        if m.id == "" {
+            m.id = m.e.progress.start("run")
+            m.e.dbg.OnProgressStart(m.id, "Running "+m.e.sourceName, true)
+        }
+        m.e.dbg.OnProgressUpdate(m.id, m.e.percent(m.e.currentLine), itoa(m.steps)+" lines executed")
+    }
+    m.cancelled = m.id != "" && m.e.progress.isCancelled()
+    return m.cancelled
+}
+
+// done ends the reported progress, if any; calling it again is a no-op.
+func (m *runMeter) done() {
+    if m.id == "" { return }
+    m.e.progress.end()
+    message := ""
+    if m.cancelled { message = "cancelled" }
+    m.e.dbg.OnProgressEnd(m.id, message)
+    m.id = ""
+}
@@ -0,0 +1,80 @@
+package engine
+
+import (
+    "sync"
+    "sync/atomic"
+)
+
+// runState tracks runs started by Go. The count goes up before the run's
+// goroutine starts, so a request handled right after continue already sees
+// the program running.
+type runState struct {
+    mu      sync.Mutex
+    runs    int
+    settles []*settle  // closed when the run settles
+    idle    *sync.Cond // signalled when runs drops to 0
+    halt    atomic.Bool // set by Stop
+}
+
+// settle is closed once a run returns or blocks waiting for input.
+type settle struct {
+    ch   chan struct{}
+    once sync.Once
+}
+
+func (s *settle) close() { s.once.Do(func() { close(s.ch) }) }
+
+// Go runs f (Continue, Next, ...) on its own goroutine; the engine counts
+// as running until f returns. The returned channel is closed once f has
+// returned or is blocked in read() waiting for input, or at once when
+// another run is already in progress. Waiting on it keeps a step
+// synchronous unless it needs input that has not arrived yet.
+func (e *Engine) Go(f func()) <-chan struct{} {
+    s := &settle{ch: make(chan struct{})}
+    e.active.mu.Lock()
+    if e.active.runs > 0 { s.close() } else { e.active.settles = append(e.active.settles, s) }
+    e.active.runs++
+    e.active.mu.Unlock()
+    go func() {
+        f()
+        e.active.mu.Lock()
+        defer e.active.mu.Unlock()
+        e.active.runs--
+        s.close()
+        if e.active.runs == 0 { e.active.settles = nil; e.active.idle.Broadcast() }
+    }()
+    return s.ch
+}
+
+// Running reports whether a run started by Go has not returned yet,
+// including one blocked in read().
+func (e *Engine) Running() bool {
+    e.active.mu.Lock()
+    defer e.active.mu.Unlock()
+    return e.active.runs > 0
+}
+
+// Stop ends the runs started by Go without reporting a stop, including one
+// blocked in read(), and waits until they have returned.
+func (e *Engine) Stop() {
+    e.inputMu.Lock()
+    e.active.halt.Store(true)
+    e.inputCond.Broadcast()
+    e.inputMu.Unlock()
+    e.active.mu.Lock()
+    for e.active.runs > 0 { e.active.idle.Wait() }
+    e.active.mu.Unlock()
+    e.active.halt.Store(false)
+}
+
+// halted reports whether Stop is ending the current run.
+func (e *Engine) halted() bool { return e.active.halt.Load() }
+
+// blocked releases callers of Go waiting for the current run to settle: it
+// is about to wait in read() for input.
+func (e *Engine) blocked() {
+    e.active.mu.Lock()
+    defer e.active.mu.Unlock()
+    for _, s := range e.active.settles { s.close() }
+    e.active.settles = nil
+}
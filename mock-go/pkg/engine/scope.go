@@ -0,0 +1,73 @@
+package engine
+
+import "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/protocol"
+
+// Well-known variablesReference values for the two scopes.
+const (
+    LocalsRef  = 1
+    GlobalsRef = 2
+)
+
+// lookup resolves name in the current frame first and then in globals.
+func (e *Engine) lookup(name string) (any, bool) {
+    if v, ok := e.locals[name]; ok { return v, true }
+    v, ok := e.globals[name]
+    return v, ok
+}
+
+// assign writes to the global unless a local of the same name shadows it;
+// unknown names become locals of the current frame.
+func (e *Engine) assign(name string, value any) {
+    if _, local := e.locals[name]; !local {
+        if _, global := e.globals[name]; global { e.globals[name] = value; return }
+    }
+    e.locals[name] = value
+}
+
+// declareGlobal makes name refer to the global scope from now on.
+func (e *Engine) declareGlobal(name string) {
+    delete(e.locals, name)
+    if _, ok := e.globals[name]; !ok { e.globals[name] = nil }
+    e.variables[name] = struct{}{}
+}
+
+// resetVariables drops the variables of an earlier launch.
+func (e *Engine) resetVariables() {
+    e.variables = map[string]struct{}{}
+    e.locals, e.globals, e.frames = map[string]any{}, map[string]any{}, nil
+    e.refs, e.refPaths, e.nextRef = map[string]int{}, map[int]varPath{}, GlobalsRef+1
+    e.mems, e.memPaths = map[string]int{}, map[int]varPath{}
+}
+
+// pushFrame gives an included file its own locals; popFrame restores the caller's.
+func (e *Engine) pushFrame() { e.frames = append(e.frames, e.locals); e.locals = map[string]any{} }
+func (e *Engine) popFrame() {
+    if len(e.frames) == 0 { return }
+    e.locals = e.frames[len(e.frames)-1]
+    e.frames = e.frames[:len(e.frames)-1]
+}
+
+// Scopes lists the scopes visible in the current frame.
+func (e *Engine) Scopes() []protocol.Scope {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    return []protocol.Scope{
+        {Name: "Locals", PresentationHint: "locals", VariablesReference: LocalsRef, NamedVariables: len(e.locals)},
+        {Name: "Globals", PresentationHint: "globals", VariablesReference: GlobalsRef, NamedVariables: len(e.globals)},
+    }
+}
+
+// Variables returns the children of a scope or structured value reference.
+func (e *Engine) Variables(ref int) ([]protocol.Variable, bool) {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    switch ref {
+    case LocalsRef, GlobalsRef: return e.listScope(ref), true
+    }
+    path, ok := e.refPaths[ref]
+    if !ok { return nil, false }
+    v, _ := e.valueAt(path)
+    fields, ok := v.([]map[string]any)
+    if !ok { return nil, false }
+    return e.listFields(path, fields), true
+}
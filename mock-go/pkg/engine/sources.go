@@ -0,0 +1,115 @@
+package engine
+
+import "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/protocol"
+
+type inlineSource struct {
+    name    string
+    content string
+}
+
+// LoadInline loads program text that has no file behind it and returns the
+// sourceReference clients use to fetch it and to set breakpoints in it.
+func (e *Engine) LoadInline(name string, contents []byte) int {
+    if name == "" { name = "inline.md" }
+    ref := e.nextSourceRef
+    e.nextSourceRef++
+    e.inline[ref] = inlineSource{name: name, content: string(contents)}
+    e.sourceFile = ""
+    e.sourceRef = ref
+    e.sourceName = name
+    e.parse(contents)
+    e.announceProgram(string(contents))
+    e.replaceBreakpoints()
+    return ref
+}
+
+// SourceRef returns the sourceReference of the loaded program (0 for files).
+func (e *Engine) SourceRef() int { return e.sourceRef }
+
+// SourceContent returns the text behind a sourceReference.
+func (e *Engine) SourceContent(ref int) (string, bool) {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    src, ok := e.inline[ref]
+    return src.content, ok
+}
+
+// sourceKey identifies a program for breakpoint bookkeeping: files by
+// absolute path, in-memory programs by reference.
+func sourceKey(path string, ref int) string {
+    if ref > 0 { return "source:" + itoa(ref) }
+    return abs(path)
+}
+
+func (e *Engine) sourceKey() string { return sourceKey(e.sourceFile, e.sourceRef) }
+
+// frameSource describes the loaded program as a DAP source.
+func (e *Engine) frameSource() protocol.Source {
+    if e.sourceRef > 0 { return protocol.Source{Name: e.sourceName, SourceReference: e.sourceRef} }
+    return protocol.Source{Name: e.sourceName, Path: e.sourceFile}
+}
+
+// unit is a loaded program unit: the program itself or an included file.
+type unit struct {
+    id      int
+    key     string
+    source  protocol.Source
+    content string
+}
+
+func (u unit) module() protocol.Module {
+    return protocol.Module{ID: u.id, Name: u.source.Name, Path: u.source.Path, IsUserCode: true, SymbolStatus: "Symbols loaded"}
+}
+
+// announceProgram reports a freshly loaded program. Loading the same program
+// again is a change; anything else replaces every loaded unit and
+// invalidates whatever the client cached about the previous program.
+func (e *Engine) announceProgram(content string) {
+    key := e.sourceKey()
+    if len(e.units) == 0 || e.units[0].key != key {
+        for _, u := range e.units {
+            e.dbg.OnLoadedSource("removed", u.source)
+            e.dbg.OnModule("removed", u.module())
+        }
+        if len(e.units) > 0 { e.dbg.OnInvalidated([]string{"threads", "stacks", "variables"}) }
+        e.units = nil
+    }
+    e.track(key, e.frameSource(), content)
+}
+
+// track records a loaded unit and announces it as new, or as changed when
+// its content differs from the last time it was loaded.
+func (e *Engine) track(key string, src protocol.Source, content string) {
+    for i, u := range e.units {
+        if u.key != key { continue }
+        if u.content == content { return }
+        e.units[i].content = content
+        e.units[i].source = src
+        e.dbg.OnLoadedSource("changed", src)
+        e.dbg.OnModule("changed", e.units[i].module())
+        return
+    }
+    u := unit{id: e.nextModuleID, key: key, source: src, content: content}
+    e.nextModuleID++
+    e.units = append(e.units, u)
+    e.dbg.OnLoadedSource("new", src)
+    e.dbg.OnModule("new", u.module())
+}
+
+// LoadedSources lists the program and every file it has included so far.
+func (e *Engine) LoadedSources() []protocol.Source {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    out := make([]protocol.Source, 0, len(e.units))
+    for _, u := range e.units { out = append(out, u.source) }
+    return out
+}
+
+// Modules pages through the loaded units and returns the total count.
+func (e *Engine) Modules(start, count int) ([]protocol.Module, int) {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    out := []protocol.Module{}
+    for i := max(start, 0); i < len(e.units) && (count <= 0 || len(out) < count); i++ { out = append(out, e.units[i].module()) }
+    return out, len(e.units)
+}
@@ -0,0 +1,384 @@
+package engine
+
+import (
+    "errors"
+    "fmt"
+    "math"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/protocol"
+)
+
+// Structured values are lists of {name, value} fields, matching the wire
+// format of PROTOCOL.md. Every structured value reachable from a scope gets
+// a variablesReference derived from its path, so references stay stable
+// while the value is edited.
+type varPath struct {
+    scope int
+    names []string
+}
+
+func (p varPath) key() string { return itoa(p.scope) + "/" + strings.Join(p.names, "/") }
+
+func (p varPath) child(name string) varPath {
+    names := append(append([]string{}, p.names...), name)
+    return varPath{scope: p.scope, names: names}
+}
+
+var errUnknownVariable = errors.New("unknown variable")
+
+var (
+    identRe    = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+    lvalueRe   = regexp.MustCompile(`^\$?([a-zA-Z][a-zA-Z0-9_]*)((?:\.[a-zA-Z0-9_]+|\[[^\]]+\])*)$`)
+    selectorRe = regexp.MustCompile(`\.([a-zA-Z0-9_]+)|\[([^\]]+)\]`)
+)
+
+// refOf returns the variablesReference for path, allocating one if needed.
+func (e *Engine) refOf(path varPath) int {
+    k := path.key()
+    if ref, ok := e.refs[k]; ok { return ref }
+    ref := e.nextRef
+    e.nextRef++
+    e.refs[k] = ref
+    e.refPaths[ref] = path
+    return ref
+}
+
+func (e *Engine) scopeMap(scope int) map[string]any {
+    if scope == GlobalsRef { return e.globals }
+    return e.locals
+}
+
+// valueAt walks path from its scope through structured fields.
+func (e *Engine) valueAt(path varPath) (any, bool) {
+    if len(path.names) == 0 { return nil, false }
+    v, ok := e.scopeMap(path.scope)[path.names[0]]
+    for _, n := range path.names[1:] {
+        if !ok { return nil, false }
+        f := field(v, n)
+        if f == nil { return nil, false }
+        v = f["value"]
+    }
+    return v, ok
+}
+
+// describe renders one variable with its type and either the reference
+// that expands a structured value or the memory backing a primitive.
+func (e *Engine) describe(path varPath, value any) protocol.Variable {
+    v := protocol.Variable{Name: path.names[len(path.names)-1], Value: value, Type: typeOf(value)}
+    if _, ok := value.([]map[string]any); ok { v.VariablesReference = e.refOf(path) }
+    if _, ok := encodeValue(value); ok { v.MemoryReference = memoryReference(e.memOf(path)) }
+    return v
+}
+
+func (e *Engine) listScope(scope int) []protocol.Variable {
+    m := e.scopeMap(scope)
+    names := make([]string, 0, len(m))
+    for k := range m { names = append(names, k) }
+    sort.Strings(names)
+    out := make([]protocol.Variable, 0, len(names))
+    for _, k := range names { out = append(out, e.describe(varPath{scope: scope, names: []string{k}}, m[k])) }
+    return out
+}
+
+func (e *Engine) listFields(path varPath, fields []map[string]any) []protocol.Variable {
+    out := make([]protocol.Variable, 0, len(fields))
+    for _, f := range fields {
+        name, _ := f["name"].(string)
+        out = append(out, e.describe(path.child(name), f["value"]))
+    }
+    return out
+}
+
+// SetVariable assigns value to name inside the container addressed by ref
+// (0 resolves name through the visible scopes). The value is coerced to the
+// variable's current type; the result carries the stored value, its type
+// and its variablesReference.
+func (e *Engine) SetVariable(ref int, name string, value any) (protocol.Variable, error) {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    var path varPath
+    switch ref {
+    case 0:
+        scope := LocalsRef
+        if _, ok := e.locals[name]; !ok { scope = GlobalsRef }
+        path = varPath{scope: scope, names: []string{name}}
+    case LocalsRef, GlobalsRef:
+        path = varPath{scope: ref, names: []string{name}}
+    default:
+        parent, ok := e.refPaths[ref]
+        if !ok { return protocol.Variable{}, fmt.Errorf("unknown variablesReference %d", ref) }
+        path = parent.child(name)
+    }
+    return e.store(path, value)
+}
+
+// SetExpression assigns the literal value to an assignable expression such
+// as `$obj.count` or `$arr[2]`, resolving the root variable like a read.
+func (e *Engine) SetExpression(expr, value string) (protocol.Variable, error) {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    path, err := e.lvalue(expr)
+    if err != nil { return protocol.Variable{}, err }
+    return e.store(path, value)
+}
+
+// Evaluate evaluates expr, which must be one whole expression, against the
+// current variables, as a debug console or watch would. It has no effect on
+// the program: read() fails instead of taking input. A plain variable
+// reference keeps its variablesReference, so structured values expand.
+func (e *Engine) Evaluate(expr string) (protocol.Variable, error) {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    p := &exprParser{e: e, s: expr, pure: true}
+    v, ok := p.or()
+    p.space()
+    if !ok || p.pos < len(p.s) { return protocol.Variable{}, fmt.Errorf("invalid expression: %s", expr) }
+    if p.err != nil { return protocol.Variable{}, p.err }
+    if ref := strings.TrimSpace(expr); refRe.FindString(ref) == ref {
+        if path, err := e.lvalue(ref); err == nil {
+            if _, ok := e.valueAt(path); !ok { return protocol.Variable{}, fmt.Errorf("%w: %s", errUnknownVariable, strings.Join(path.names, ".")) }
+            d := e.describe(path, v)
+            d.Name = ref
+            return d, nil
+        }
+    }
+    return protocol.Variable{Name: expr, Value: v, Type: typeOf(v)}, nil
+}
+
+// lvalue turns an expression into the path of the location it denotes.
+func (e *Engine) lvalue(expr string) (varPath, error) {
+    m := lvalueRe.FindStringSubmatch(strings.TrimSpace(expr))
+    if m == nil { return varPath{}, fmt.Errorf("expression is not assignable: %s", expr) }
+    scope := LocalsRef
+    if _, ok := e.locals[m[1]]; !ok { scope = GlobalsRef }
+    path := varPath{scope: scope, names: []string{m[1]}}
+    for _, sel := range selectorRe.FindAllStringSubmatch(m[2], -1) {
+        name := sel[1]
+        if name == "" {
+            var err error
+            if name, err = e.index(strings.TrimSpace(sel[2])); err != nil { return varPath{}, err }
+        }
+        path = path.child(name)
+    }
+    return path, nil
+}
+
+// index resolves the text between the brackets of a selector. It is an
+// expression, evaluated without reading input, whose value (an integer or
+// a string) names the element; a bare name such as `[count]` is taken
+// literally.
+func (e *Engine) index(src string) (string, error) {
+    p := &exprParser{e: e, s: src, pure: true}
+    v, ok := p.or()
+    p.space()
+    if !ok || p.pos < len(src) {
+        if identRe.MatchString(src) { return src, nil }
+        return "", fmt.Errorf("invalid index: [%s]", src)
+    }
+    if p.err != nil { return "", p.err }
+    switch t := v.(type) {
+    case int: return itoa(t), nil
+    case string: return t, nil
+    case float64: if t == math.Trunc(t) { return itoa(int(t)), nil }
+    }
+    return "", fmt.Errorf("index [%s] is %s, not an integer or string", src, typeOf(v))
+}
+
+// store coerces value to the type found at path and writes it there.
+func (e *Engine) store(path varPath, value any) (protocol.Variable, error) {
+    cur, ok := e.valueAt(path)
+    if !ok { return protocol.Variable{}, fmt.Errorf("%w: %s", errUnknownVariable, strings.Join(path.names, ".")) }
+    v, err := coerce(cur, value)
+    if err != nil { return protocol.Variable{}, fmt.Errorf("%s: %v", strings.Join(path.names, "."), err) }
+    e.put(path, v)
+    return e.describe(path, v), nil
+}
+
+// put writes v at an existing path without conversion.
+func (e *Engine) put(path varPath, v any) {
+    if len(path.names) == 1 { e.scopeMap(path.scope)[path.names[0]] = v; return }
+    parent, _ := e.valueAt(varPath{scope: path.scope, names: path.names[:len(path.names)-1]})
+    if f := field(parent, path.names[len(path.names)-1]); f != nil { f["value"] = v }
+}
+
+// NotifyWrite reports a client-side write of name (inside ref, or an
+// assignable expression): the client refetches variables, and data
+// breakpoints watching the root variable for write access stop.
+func (e *Engine) NotifyWrite(ref int, name string) {
+    e.execMu.Lock()
+    defer e.execMu.Unlock()
+    e.dbg.OnInvalidated([]string{"variables"})
+    root := name
+    if p, ok := e.refPaths[ref]; ok && len(p.names) > 0 {
+        root = p.names[0]
+    } else if m := lvalueRe.FindStringSubmatch(strings.TrimSpace(name)); m != nil {
+        root = m[1]
+    }
+    if flg, ok := e.dataBps[root]; ok && strings.Contains(flg, "write") && !e.noDebug {
+        e.dbg.OnStopOnDataBreakpoint(e.currentLine, e.currentCol)
+    }
+}
+
+func field(v any, name string) map[string]any {
+    fields, ok := v.([]map[string]any)
+    if !ok { return nil }
+    for _, f := range fields { if f["name"] == name { return f } }
+    return nil
+}
+
+func typeOf(v any) string {
+    switch v.(type) {
+    case nil: return "null"
+    case bool: return "boolean"
+    case int: return "integer"
+    case float64: return "float"
+    case string: return "string"
+    case []map[string]any: return "object"
+    }
+    return "unknown"
+}
+
+// normalize converts decoded JSON into engine values: integral numbers
+// become int, and arrays and objects become structured values. An array of
+// {name, value} pairs (the wire form of a structured value) keeps its
+// names; other arrays are indexed and objects use their sorted keys.
+func normalize(v any) any {
+    switch t := v.(type) {
+    case float64:
+        if t == math.Trunc(t) && math.Abs(t) < 1<<53 { return int(t) }
+    case []any:
+        fields := make([]map[string]any, 0, len(t))
+        for i, el := range t {
+            if m, ok := el.(map[string]any); ok && isPair(m) {
+                fields = append(fields, map[string]any{"name": fmt.Sprint(m["name"]), "value": normalize(m["value"])})
+            } else {
+                fields = append(fields, map[string]any{"name": itoa(i), "value": normalize(el)})
+            }
+        }
+        return fields
+    case map[string]any:
+        names := make([]string, 0, len(t))
+        for k := range t { names = append(names, k) }
+        sort.Strings(names)
+        fields := make([]map[string]any, 0, len(t))
+        for _, k := range names { fields = append(fields, map[string]any{"name": k, "value": normalize(t[k])}) }
+        return fields
+    case []map[string]any:
+        return t
+    }
+    return v
+}
+
+// isPair reports whether m is exactly a {name, value} field.
+func isPair(m map[string]any) bool {
+    _, name := m["name"]
+    _, value := m["value"]
+    return name && value && len(m) == 2
+}
+
+// coerce converts a client-supplied value to the type of cur. Strings are
+// parsed as mock-language literals when the target is not a string.
+func coerce(cur, value any) (any, error) {
+    v := normalize(value)
+    if s, ok := v.(string); ok {
+        if _, target := cur.(string); target { return unquote(s), nil }
+        v = parseToken(strings.TrimSpace(s))
+    }
+    switch cur.(type) {
+    case nil:
+        if typeOf(v) != "unknown" { return v, nil }
+    case bool:
+        if b, ok := v.(bool); ok { return b, nil }
+    case int:
+        switch t := v.(type) {
+        case int: return t, nil
+        case float64: if t == math.Trunc(t) { return int(t), nil }
+        }
+    case float64:
+        switch t := v.(type) {
+        case int: return float64(t), nil
+        case float64: return t, nil
+        }
+    case string:
+        switch t := v.(type) {
+        case bool, int, float64: return formatValue(t), nil
+        }
+    case []map[string]any:
+        if t, ok := v.([]map[string]any); ok { return t, nil }
+    }
+    return nil, fmt.Errorf("cannot assign %s to %s variable", typeOf(v), typeOf(cur))
+}
+
+func unquote(s string) string {
+    if len(s) >= 2 && strings.HasPrefix(s, "\"") && strings.HasSuffix(s, "\"") { return s[1 : len(s)-1] }
+    return s
+}
+
+func formatValue(v any) string {
+    switch t := v.(type) {
+    case float64: return strconv.FormatFloat(t, 'g', -1, 64)
+    case []map[string]any: return "{...}"
+    }
+    return fmt.Sprint(v)
+}
+
+// parseToken is the literal parser of the mock language. Besides
+// primitives it accepts arrays `[1, "a"]` and objects `{count: 1}`; any
+// other braced text yields the demo object shared with the C#/TS engines.
+func parseToken(t string) any {
+    switch {
+    case t == "true":
+        return true
+    case t == "false":
+        return false
+    case strings.HasPrefix(t, "\"") && strings.HasSuffix(t, "\""):
+        return strings.Trim(t, "\"")
+    case strings.HasPrefix(t, "[") && strings.HasSuffix(t, "]"):
+        fields := []map[string]any{}
+        for i, el := range splitTop(t[1 : len(t)-1]) { fields = append(fields, map[string]any{"name": itoa(i), "value": parseToken(el)}) }
+        return fields
+    case strings.HasPrefix(t, "{"):
+        if obj, ok := parseObject(t); ok { return obj }
+        return []map[string]any{{"name": "fBool", "value": true}, {"name": "fInteger", "value": 123}, {"name": "fString", "value": "hello"}, {"name": "flazyInteger", "value": 321}}
+    }
+    if i, err := strconv.Atoi(t); err == nil { return i }
+    if f, err := strconv.ParseFloat(t, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) { return f }
+    return t
+}
+
+func parseObject(t string) ([]map[string]any, bool) {
+    if !strings.HasSuffix(t, "}") { return nil, false }
+    parts := splitTop(t[1 : len(t)-1])
+    if len(parts) == 0 { return nil, false }
+    fields := make([]map[string]any, 0, len(parts))
+    for _, part := range parts {
+        k, v, ok := strings.Cut(part, ":")
+        k = strings.TrimSpace(k)
+        if !ok || !identRe.MatchString(k) { return nil, false }
+        fields = append(fields, map[string]any{"name": k, "value": parseToken(strings.TrimSpace(v))})
+    }
+    return fields, true
+}
+
+// splitTop splits s on commas outside of quotes and brackets.
+func splitTop(s string) []string {
+    var out []string
+    depth, start, quoted := 0, 0, false
+    for i := 0; i < len(s); i++ {
+        switch c := s[i]; {
+        case c == '"': quoted = !quoted
+        case quoted:
+        case c == '[' || c == '{': depth++
+        case c == ']' || c == '}': depth--
+        case c == ',' && depth == 0:
+            out = append(out, strings.TrimSpace(s[start:i]))
+            start = i + 1
+        }
+    }
+    if rest := strings.TrimSpace(s[start:]); rest != "" || len(out) > 0 { out = append(out, rest) }
+    return out
+}
@@ -0,0 +1,81 @@
+package engine
+
+import (
+    "os"
+    "strings"
+    "time"
+)
+
+// Watch polls the loaded program file every interval and calls changed
+// with its new content when it changes, until the returned stop function is
+// called. changed runs on the watcher's goroutine: hand the content to the
+// goroutine driving the engine, which applies it with Reload. Polling keeps
+// it portable; in-memory programs are not watched.
+func (e *Engine) Watch(interval time.Duration, changed func(path string, contents []byte)) (stop func()) {
+    done := make(chan struct{})
+    path := e.sourceFile
+    if path == "" { return func() {} }
+    last, _ := os.Stat(path)
+    go func() {
+        t := time.NewTicker(interval)
+        defer t.Stop()
+        for {
+            select {
+            case <-done:
+                return
+            case <-t.C:
+            }
+            fi, err := os.Stat(path)
+            if err != nil || (last != nil && fi.ModTime().Equal(last.ModTime()) && fi.Size() == last.Size()) { continue }
+            last = fi
+            data, err := os.ReadFile(path)
+            if err != nil { continue }
+            changed(path, data)
+        }
+    }()
+    return func() { close(done) }
+}
+
+// Reload replaces the program text of path while keeping the session: the
+// current position moves to the nearest line with the same text, breakpoints
+// are placed again and the client is told to refresh its stacks. It never
+// waits for a run: while the program is running it does nothing and reports
+// false, and the caller tries again once the run has settled.
+func (e *Engine) Reload(path string, contents []byte) bool {
+    if e.Running() || !e.execMu.TryLock() { return false }
+    defer e.execMu.Unlock()
+    if e.sourceRef != 0 || abs(path) != e.sourceFile { return true }
+    if len(e.units) > 0 && e.units[0].content == string(contents) { return true }
+
+    oldLines, oldLine, oldCol := e.sourceLines, e.currentLine, e.currentCol
+    e.parse(contents)
+    e.currentLine = mapLine(oldLines, e.sourceLines, oldLine)
+    if e.currentLine < len(e.starts) { e.instruction = e.starts[e.currentLine] }
+    if e.getLine(e.currentLine) == lineAt(oldLines, oldLine) { e.currentCol = oldCol }
+    e.announceProgram(string(contents))
+
+    e.replaceBreakpoints()
+    e.dbg.OnInvalidated([]string{"stacks"})
+    return true
+}
+
+// mapLine finds the line of newLines equivalent to oldLines[ln]: the nearest
+// line with identical text, else the same index clamped to the new length.
+func mapLine(oldLines, newLines []string, ln int) int {
+    want := strings.TrimSpace(lineAt(oldLines, ln))
+    if want != "" {
+        for d := 0; d < len(newLines)+ln+1; d++ {
+            for _, c := range []int{ln - d, ln + d} {
+                if c >= 0 && c < len(newLines) && strings.TrimSpace(newLines[c]) == want { return c }
+            }
+        }
+    }
+    if ln >= len(newLines) { ln = len(newLines) - 1 }
+    if ln < 0 { ln = 0 }
+    return ln
+}
+
+func lineAt(lines []string, ln int) string {
+    if ln < 0 || ln >= len(lines) { return "" }
+    return lines[ln]
+}
@@ -0,0 +1,176 @@
+package protocol
+
+// Arguments of the requests. Fields without omitempty are required; the
+// others default to their zero value unless documented otherwise. Unknown
+// fields are ignored so clients can pass their whole launch configuration.
+
+// InitializeArgs asks for a protocol version; CurrentVersion when empty.
+type InitializeArgs struct {
+    ProtocolVersion string `json:"protocolVersion,omitempty"`
+}
+
+type AttachArgs struct {
+    StopOnAttach bool `json:"stopOnAttach,omitempty"`
+}
+
+// LaunchArgs runs Program, or ProgramText as an in-memory program named
+// ProgramName. Env values may be null, which leaves the variable unset.
+type LaunchArgs struct {
+    Program             string             `json:"program,omitempty"`
+    ProgramText         *string            `json:"programText,omitempty"`
+    ProgramName         string             `json:"programName,omitempty"`
+    StopOnEntry         bool               `json:"stopOnEntry,omitempty"`
+    NoDebug             bool               `json:"noDebug,omitempty"`
+    Args                []string           `json:"args,omitempty"`
+    Env                 map[string]*string `json:"env,omitempty"`
+    Cwd                 string             `json:"cwd,omitempty"`
+    Stdin               string             `json:"stdin,omitempty"`
+    Watch               bool               `json:"watch,omitempty"`
+    RelocateBreakpoints *bool              `json:"relocateBreakpoints,omitempty"`
+}
+
+type InputArgs struct {
+    Text string `json:"text"`
+}
+
+// SetBreakpointsArgs replaces the breakpoints of Path, or of the in-memory
+// program SourceReference.
+type SetBreakpointsArgs struct {
+    Path            string `json:"path,omitempty"`
+    SourceReference int    `json:"sourceReference,omitempty"`
+    Lines           []int  `json:"lines,omitempty"`
+}
+
+type SourceArgs struct {
+    SourceReference int `json:"sourceReference"`
+}
+
+type LoadedSourcesArgs struct{}
+
+// ModulesArgs pages through modules; a ModuleCount of 0 means all.
+type ModulesArgs struct {
+    StartModule int `json:"startModule,omitempty"`
+    ModuleCount int `json:"moduleCount,omitempty"`
+}
+
+type ContinueArgs struct {
+    Reverse bool `json:"reverse,omitempty"`
+}
+
+type DisconnectArgs struct{}
+
+type PauseArgs struct{}
+
+// CancelArgs cancels the progress ProgressID, or the current one when empty.
+type CancelArgs struct {
+    ProgressID string `json:"progressId,omitempty"`
+}
+
+type GotoTargetsArgs struct {
+    Line int `json:"line"`
+}
+
+type GotoArgs struct {
+    TargetID int `json:"targetId"`
+}
+
+type NextArgs struct {
+    Reverse bool `json:"reverse,omitempty"`
+}
+
+type StepInArgs struct {
+    TargetID *int `json:"targetId,omitempty"`
+}
+
+type StepOutArgs struct{}
+
+// StackTraceArgs returns Levels frames (1000 when absent) from StartFrame.
+type StackTraceArgs struct {
+    StartFrame int  `json:"startFrame,omitempty"`
+    Levels     *int `json:"levels,omitempty"`
+}
+
+type BreakpointLocationsArgs struct {
+    Path string `json:"path,omitempty"`
+    Line int    `json:"line"`
+}
+
+type BreakpointLinesArgs struct{}
+
+// DisassembleArgs lists InstructionCount instructions (32 when absent).
+type DisassembleArgs struct {
+    Address          int  `json:"address,omitempty"`
+    InstructionCount *int `json:"instructionCount,omitempty"`
+}
+
+type GetLocalVariablesArgs struct{}
+
+type GetLocalVariableArgs struct {
+    Name string `json:"name"`
+}
+
+// SetVariableArgs assigns Value to Name inside VariablesReference; 0
+// resolves Name through the visible scopes.
+type SetVariableArgs struct {
+    VariablesReference int    `json:"variablesReference,omitempty"`
+    Name               string `json:"name"`
+    Value              any    `json:"value"`
+}
+
+type ScopesArgs struct{}
+
+type VariablesArgs struct {
+    VariablesReference int `json:"variablesReference"`
+}
+
+type SetExpressionArgs struct {
+    Expression string `json:"expression"`
+    Value      string `json:"value"`
+}
+
+type EvaluateArgs struct {
+    Expression string `json:"expression"`
+}
+
+// CompletionsArgs completes Text with the cursor at Column (the end when
+// absent).
+type CompletionsArgs struct {
+    Text   string `json:"text"`
+    Column *int   `json:"column,omitempty"`
+}
+
+type ReadMemoryArgs struct {
+    MemoryReference string `json:"memoryReference"`
+    Offset          int    `json:"offset,omitempty"`
+    Count           int    `json:"count"`
+}
+
+// WriteMemoryArgs writes the base64 Data at Offset.
+type WriteMemoryArgs struct {
+    MemoryReference string `json:"memoryReference"`
+    Offset          int    `json:"offset,omitempty"`
+    Data            string `json:"data"`
+    AllowPartial    bool   `json:"allowPartial,omitempty"`
+}
+
+type GetGlobalVariablesArgs struct{}
+
+type SetExceptionBreakpointsArgs struct {
+    NamedException  string `json:"namedException,omitempty"`
+    OtherExceptions bool   `json:"otherExceptions,omitempty"`
+}
+
+// SetDataBreakpointArgs watches the variable Address for AccessType
+// ("read", "write" or "readWrite").
+type SetDataBreakpointArgs struct {
+    Address    string `json:"address"`
+    AccessType string `json:"accessType,omitempty"`
+}
+
+type ClearAllDataBreakpointsArgs struct{}
+
+type SetInstructionBreakpointArgs struct {
+    Address int `json:"address"`
+}
+
+type ClearInstructionBreakpointsArgs struct{}
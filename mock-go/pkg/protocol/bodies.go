@@ -0,0 +1,115 @@
+package protocol
+
+// Bodies of the successful responses. Requests not listed here answer
+// without a body.
+
+// Capabilities are the optional features a runtime supports.
+type Capabilities struct{}
+
+// InitializeBody confirms the version the session speaks and lists the
+// versions the runtime supports.
+type InitializeBody struct {
+    ProtocolVersion   string       `json:"protocolVersion"`
+    SupportedVersions []string     `json:"supportedVersions"`
+    Capabilities      Capabilities `json:"capabilities"`
+}
+
+type AttachBody struct {
+    Program      string `json:"program"`
+    SourceLength int    `json:"sourceLength"`
+}
+
+// LaunchBody is only sent for in-memory programs.
+type LaunchBody struct {
+    SourceReference int `json:"sourceReference,omitempty"`
+}
+
+type SetBreakpointsBody struct {
+    Breakpoints []Breakpoint `json:"breakpoints"`
+}
+
+type SourceBody struct {
+    Content  string `json:"content"`
+    MimeType string `json:"mimeType"`
+}
+
+type LoadedSourcesBody struct {
+    Sources []Source `json:"sources"`
+}
+
+type ModulesBody struct {
+    Modules      []Module `json:"modules"`
+    TotalModules int      `json:"totalModules"`
+}
+
+type GotoTargetsBody struct {
+    Targets []GotoTarget `json:"targets"`
+}
+
+type StackTraceBody struct {
+    StackFrames []StackFrame `json:"stackFrames"`
+    TotalFrames int          `json:"totalFrames"`
+}
+
+type BreakpointLocationsBody struct {
+    Breakpoints []BreakpointLocation `json:"breakpoints"`
+}
+
+type BreakpointLinesBody struct {
+    Lines []int `json:"lines"`
+}
+
+type DisassembleBody struct {
+    Instructions []DisassembledInstruction `json:"instructions"`
+}
+
+// VariablesBody answers variables, getLocalVariables and getGlobalVariables.
+type VariablesBody struct {
+    Variables []Variable `json:"variables"`
+}
+
+// GetLocalVariableBody has a null Variable when there is no such local.
+type GetLocalVariableBody struct {
+    Variable *NamedValue `json:"variable"`
+}
+
+// SetVariableBody answers setVariable and setExpression with the stored
+// value.
+type SetVariableBody struct {
+    Value              any    `json:"value"`
+    Type               string `json:"type"`
+    VariablesReference int    `json:"variablesReference"`
+    MemoryReference    string `json:"memoryReference,omitempty"`
+}
+
+// EvaluateBody is the value of an expression; VariablesReference is set
+// when the expression is a structured variable.
+type EvaluateBody struct {
+    Value              any    `json:"value"`
+    Type               string `json:"type"`
+    VariablesReference int    `json:"variablesReference"`
+}
+
+type ScopesBody struct {
+    Scopes []Scope `json:"scopes"`
+}
+
+type CompletionsBody struct {
+    Targets []CompletionItem `json:"targets"`
+}
+
+// ReadMemoryBody carries base64 Data read at Address.
+type ReadMemoryBody struct {
+    Address         string `json:"address"`
+    Data            string `json:"data"`
+    UnreadableBytes int    `json:"unreadableBytes,omitempty"`
+}
+
+type WriteMemoryBody struct {
+    BytesWritten int `json:"bytesWritten"`
+}
+
+// VerifiedBody answers setDataBreakpoint and setInstructionBreakpoint.
+type VerifiedBody struct {
+    Verified bool `json:"verified"`
+}
@@ -0,0 +1,49 @@
+package protocol
+
+// Command describes a request: its arguments and the body of its success
+// response (nil when it has none).
+type Command struct {
+    Args any
+    Body any
+}
+
+// Commands lists every request of PROTOCOL.md.
+var Commands = map[string]Command{
+    "initialize":                  {InitializeArgs{}, InitializeBody{}},
+    "attach":                      {AttachArgs{}, AttachBody{}},
+    "launch":                      {LaunchArgs{}, LaunchBody{}},
+    "input":                       {InputArgs{}, nil},
+    "setBreakpoints":              {SetBreakpointsArgs{}, SetBreakpointsBody{}},
+    "source":                      {SourceArgs{}, SourceBody{}},
+    "loadedSources":               {LoadedSourcesArgs{}, LoadedSourcesBody{}},
+    "modules":                     {ModulesArgs{}, ModulesBody{}},
+    "continue":                    {ContinueArgs{}, nil},
+    "disconnect":                  {DisconnectArgs{}, nil},
+    "pause":                       {PauseArgs{}, nil},
+    "cancel":                      {CancelArgs{}, nil},
+    "gotoTargets":                 {GotoTargetsArgs{}, GotoTargetsBody{}},
+    "goto":                        {GotoArgs{}, nil},
+    "next":                        {NextArgs{}, nil},
+    "stepIn":                      {StepInArgs{}, nil},
+    "stepOut":                     {StepOutArgs{}, nil},
+    "stackTrace":                  {StackTraceArgs{}, StackTraceBody{}},
+    "breakpointLocations":         {BreakpointLocationsArgs{}, BreakpointLocationsBody{}},
+    "breakpointLines":             {BreakpointLinesArgs{}, BreakpointLinesBody{}},
+    "disassemble":                 {DisassembleArgs{}, DisassembleBody{}},
+    "getLocalVariables":           {GetLocalVariablesArgs{}, VariablesBody{}},
+    "getLocalVariable":            {GetLocalVariableArgs{}, GetLocalVariableBody{}},
+    "setVariable":                 {SetVariableArgs{}, SetVariableBody{}},
+    "scopes":                      {ScopesArgs{}, ScopesBody{}},
+    "variables":                   {VariablesArgs{}, VariablesBody{}},
+    "setExpression":               {SetExpressionArgs{}, SetVariableBody{}},
+    "evaluate":                    {EvaluateArgs{}, EvaluateBody{}},
+    "completions":                 {CompletionsArgs{}, CompletionsBody{}},
+    "readMemory":                  {ReadMemoryArgs{}, ReadMemoryBody{}},
+    "writeMemory":                 {WriteMemoryArgs{}, WriteMemoryBody{}},
+    "getGlobalVariables":          {GetGlobalVariablesArgs{}, VariablesBody{}},
+    "setExceptionBreakpoints":     {SetExceptionBreakpointsArgs{}, nil},
+    "setDataBreakpoint":           {SetDataBreakpointArgs{}, VerifiedBody{}},
+    "clearAllDataBreakpoints":     {ClearAllDataBreakpointsArgs{}, nil},
+    "setInstructionBreakpoint":    {SetInstructionBreakpointArgs{}, VerifiedBody{}},
+    "clearInstructionBreakpoints": {ClearInstructionBreakpointsArgs{}, nil},
+}
@@ -0,0 +1,90 @@
+package protocol
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "reflect"
+    "strings"
+)
+
+// Decode decodes the request's arguments into v, a pointer to a struct,
+// and fails with a message naming the offending field when an argument
+// has the wrong type or a required one (no omitempty) is missing. Absent
+// arguments decode as an empty object.
+func (r Request) Decode(v any) error {
+    raw := r.Args
+    if len(raw) == 0 || string(raw) == "null" { raw = json.RawMessage("{}") }
+    var present map[string]json.RawMessage
+    if err := json.Unmarshal(raw, &present); err != nil { return r.argsError("arguments must be an object") }
+    if t := reflect.TypeOf(v); t.Kind() == reflect.Pointer && t.Elem().Kind() == reflect.Struct {
+        for _, f := range fields(t.Elem()) {
+            val, ok := present[f.name]
+            missing := !ok || (string(val) == "null" && f.typ.Kind() != reflect.Interface)
+            if f.required && missing { return r.argsError(f.name + " is required") }
+        }
+    }
+    if err := json.Unmarshal(raw, v); err != nil {
+        var te *json.UnmarshalTypeError
+        if errors.As(err, &te) {
+            name := te.Field
+            if name == "" { name = "arguments" }
+            return r.argsError(fmt.Sprintf("%s must be %s, not %s", name, jsonType(te.Type), jsonValue(te.Value)))
+        }
+        return r.argsError(err.Error())
+    }
+    return nil
+}
+
+func (r Request) argsError(msg string) error {
+    return fmt.Errorf("invalid arguments for %s: %s", r.Command, msg)
+}
+
+// field is a JSON-visible struct field.
+type field struct {
+    name     string
+    typ      reflect.Type
+    required bool
+}
+
+// fields lists the JSON fields of struct type t; fields without omitempty
+// are required.
+func fields(t reflect.Type) []field {
+    var out []field
+    for i := 0; i < t.NumField(); i++ {
+        sf := t.Field(i)
+        if !sf.IsExported() { continue }
+        tag := sf.Tag.Get("json")
+        if tag == "-" { continue }
+        name, opts, _ := strings.Cut(tag, ",")
+        if name == "" { name = sf.Name }
+        out = append(out, field{name: name, typ: sf.Type, required: !strings.Contains(opts, "omitempty")})
+    }
+    return out
+}
+
+// jsonValue names the JSON value decoding found: its type, or a number
+// that did not fit.
+func jsonValue(v string) string {
+    switch v {
+    case "bool": return "a boolean"
+    case "number", "string", "null": return "a " + v
+    case "array", "object": return "an " + v
+    }
+    return strings.TrimPrefix(v, "number ")
+}
+
+// jsonType names the JSON type a Go type decodes from.
+func jsonType(t reflect.Type) string {
+    switch t.Kind() {
+    case reflect.Pointer: return jsonType(t.Elem())
+    case reflect.Bool: return "a boolean"
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64: return "an integer"
+    case reflect.Float32, reflect.Float64: return "a number"
+    case reflect.String: return "a string"
+    case reflect.Slice, reflect.Array: return "an array"
+    case reflect.Map, reflect.Struct: return "an object"
+    }
+    return "a value"
+}
@@ -0,0 +1,3 @@
+// Package protocol holds the envelopes of the line-delimited JSON protocol
+// described in PROTOCOL.md: requests, responses and events.
+package protocol
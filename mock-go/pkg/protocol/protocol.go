@@ -1,10 +1,24 @@
 package protocol
 
+import "encoding/json"
+
+// Request is a client request. Args holds the raw arguments; Decode them
+// into the command's *Args struct.
 type Request struct {
-    Type    string                 `json:"type"`
-    ID      int                    `json:"id"`
-    Command string                 `json:"command"`
-    Args    map[string]any         `json:"args,omitempty"`
+    Type    string          `json:"type"`
+    ID      int             `json:"id"`
+    Command string          `json:"command"`
+    Args    json.RawMessage `json:"args,omitempty"`
+}
+
+// NewRequest builds a request with args encoded; nil args are left out.
+func NewRequest(id int, command string, args any) (Request, error) {
+    req := Request{Type: "request", ID: id, Command: command}
+    if args == nil { return req, nil }
+    raw, err := json.Marshal(args)
+    if err != nil { return req, err }
+    req.Args = raw
+    return req, nil
 }
 
 type Response struct {
@@ -32,4 +46,3 @@ func OkEmpty(id int) Response {
 func Fail(id int, msg string) Response {
     return Response{Type: "response", ID: id, Success: false, Message: msg}
 }
-
@@ -0,0 +1,106 @@
+package protocol
+
+import (
+    "encoding/json"
+    "reflect"
+    "sort"
+    "strings"
+)
+
+//go:generate sh -c "go run ../../cmd/mock-go --schema > ../../../protocol.schema.json"
+
+// Schema returns a JSON Schema (draft 2020-12) of the protocol. A message is
+// any of the commands' requests, a response or an event; the response of
+// each command, argument and body types are under $defs.
+func Schema() map[string]any {
+    defs := map[string]any{}
+    names := make([]string, 0, len(Commands))
+    for name := range Commands { names = append(names, name) }
+    sort.Strings(names)
+
+    messages := []any{}
+    for _, name := range names {
+        c := Commands[name]
+        args := schemaOf(reflect.TypeOf(c.Args), defs)
+        req := envelope("request", map[string]any{"command": map[string]any{"const": name}, "args": args}, "command")
+        if required(reflect.TypeOf(c.Args)) { req["required"] = []string{"type", "id", "command", "args"} }
+        if v, ok := Removed[name]; ok { req["deprecated"], req["description"] = true, "removed in protocol "+v }
+        defs[name+"Request"] = req
+        body := map[string]any{"not": map[string]any{}}
+        if c.Body != nil { body = schemaOf(reflect.TypeOf(c.Body), defs) }
+        defs[name+"Response"] = response(body)
+        messages = append(messages, ref(name+"Request"))
+    }
+    defs["Response"] = response(map[string]any{})
+    defs["Event"] = map[string]any{
+        "type": "object",
+        "properties": map[string]any{"type": map[string]any{"const": "event"}, "event": map[string]any{"type": "string"}, "body": map[string]any{"type": "object"}},
+        "required": []string{"type", "event"},
+    }
+    messages = append(messages, ref("Response"), ref("Event"))
+    return map[string]any{
+        "$schema": "https://json-schema.org/draft/2020-12/schema",
+        "title":   "Mock runtime protocol message",
+        "description": "Protocol versions " + strings.Join(Versions, ", ") + "; sessions that do not negotiate one in initialize speak " + CurrentVersion + ".",
+        "anyOf":   messages,
+        "$defs":   defs,
+    }
+}
+
+// SchemaJSON is Schema indented for writing to a file.
+func SchemaJSON() ([]byte, error) { return json.MarshalIndent(Schema(), "", "  ") }
+
+func envelope(kind string, props map[string]any, required ...string) map[string]any {
+    props["type"] = map[string]any{"const": kind}
+    props["id"] = map[string]any{"type": "integer"}
+    return map[string]any{"type": "object", "properties": props, "required": append([]string{"type", "id"}, required...)}
+}
+
+func response(body map[string]any) map[string]any {
+    return envelope("response", map[string]any{"success": map[string]any{"type": "boolean"}, "message": map[string]any{"type": "string"}, "body": body}, "success")
+}
+
+func ref(name string) map[string]any { return map[string]any{"$ref": "#/$defs/" + name} }
+
+// required reports whether struct type t has required fields.
+func required(t reflect.Type) bool {
+    for _, f := range fields(t) { if f.required { return true } }
+    return false
+}
+
+// schemaOf describes t, adding named structs to defs.
+func schemaOf(t reflect.Type, defs map[string]any) map[string]any {
+    if t == reflect.TypeOf(json.RawMessage{}) { return map[string]any{} }
+    switch t.Kind() {
+    case reflect.Pointer: return schemaOf(t.Elem(), defs)
+    case reflect.Interface: return map[string]any{}
+    case reflect.Bool: return map[string]any{"type": "boolean"}
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        return map[string]any{"type": "integer"}
+    case reflect.Float32, reflect.Float64: return map[string]any{"type": "number"}
+    case reflect.String: return map[string]any{"type": "string"}
+    case reflect.Slice, reflect.Array: return map[string]any{"type": "array", "items": schemaOf(t.Elem(), defs)}
+    case reflect.Map: return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem(), defs)}
+    case reflect.Struct:
+        if _, ok := defs[t.Name()]; !ok {
+            defs[t.Name()] = nil // break cycles
+            props, req := map[string]any{}, []string{}
+            for _, f := range fields(t) {
+                s := schemaOf(f.typ, defs)
+                // a required pointer is sent as null when unset
+                if f.required && f.typ.Kind() == reflect.Pointer { s = map[string]any{"anyOf": []any{s, map[string]any{"type": "null"}}} }
+                if f.typ.Kind() == reflect.Map && f.typ.Elem().Kind() == reflect.Pointer {
+                    s["additionalProperties"] = map[string]any{"anyOf": []any{schemaOf(f.typ.Elem(), defs), map[string]any{"type": "null"}}}
+                }
+                props[f.name] = s
+                if f.required { req = append(req, f.name) }
+            }
+            def := map[string]any{"type": "object", "properties": props}
+            if len(req) > 0 { def["required"] = req }
+            defs[t.Name()] = def
+        }
+        return ref(t.Name())
+    }
+    return map[string]any{}
+}
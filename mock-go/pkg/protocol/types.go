@@ -0,0 +1,94 @@
+package protocol
+
+// Source is a program file, or an in-memory program identified by its
+// SourceReference.
+type Source struct {
+    Name            string `json:"name,omitempty"`
+    Path            string `json:"path,omitempty"`
+    SourceReference int    `json:"sourceReference,omitempty"`
+}
+
+// Breakpoint is a line breakpoint as the runtime placed it. Line differs
+// from the requested line when the breakpoint was relocated.
+type Breakpoint struct {
+    ID       int     `json:"id"`
+    Line     int     `json:"line"`
+    Column   *int    `json:"column,omitempty"`
+    Verified bool    `json:"verified"`
+    Message  string  `json:"message,omitempty"`
+    Source   *Source `json:"source,omitempty"`
+}
+
+// BreakpointLocation is a column of a line where a breakpoint can go.
+type BreakpointLocation struct {
+    Column int `json:"column"`
+}
+
+// StackFrame is one frame of the current stack.
+type StackFrame struct {
+    ID     int     `json:"id"`
+    Name   string  `json:"name"`
+    Source *Source `json:"source,omitempty"`
+    Line   int     `json:"line"`
+    Column int     `json:"column"`
+}
+
+// Scope is a variable container of the current frame.
+type Scope struct {
+    Name               string `json:"name"`
+    PresentationHint   string `json:"presentationHint"`
+    VariablesReference int    `json:"variablesReference"`
+    NamedVariables     int    `json:"namedVariables"`
+    Expensive          bool   `json:"expensive"`
+}
+
+// Variable is a variable or field. Structured values have a
+// VariablesReference to expand them; primitives have a MemoryReference.
+type Variable struct {
+    Name               string `json:"name"`
+    Value              any    `json:"value"`
+    Type               string `json:"type"`
+    VariablesReference int    `json:"variablesReference"`
+    MemoryReference    string `json:"memoryReference,omitempty"`
+}
+
+// NamedValue is a variable's raw value.
+type NamedValue struct {
+    Name  string `json:"name"`
+    Value any    `json:"value"`
+}
+
+// CompletionItem replaces Length characters at Start with Text (Label when
+// empty). SelectionStart is where the cursor goes inside the inserted text.
+type CompletionItem struct {
+    Label          string `json:"label"`
+    Text           string `json:"text,omitempty"`
+    Type           string `json:"type"`
+    Start          int    `json:"start"`
+    Length         int    `json:"length"`
+    SelectionStart int    `json:"selectionStart,omitempty"`
+}
+
+// GotoTarget is a place execution can jump to.
+type GotoTarget struct {
+    ID    int    `json:"id"`
+    Label string `json:"label"`
+    Line  int    `json:"line"`
+}
+
+// DisassembledInstruction is one instruction; addresses past the program
+// are "nop" and have no line.
+type DisassembledInstruction struct {
+    Address     int    `json:"address"`
+    Instruction string `json:"instruction"`
+    Line        *int   `json:"line,omitempty"`
+}
+
+// Module is a loaded program unit.
+type Module struct {
+    ID           int    `json:"id"`
+    Name         string `json:"name"`
+    Path         string `json:"path,omitempty"`
+    IsUserCode   bool   `json:"isUserCode"`
+    SymbolStatus string `json:"symbolStatus"`
+}
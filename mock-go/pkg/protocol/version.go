@@ -0,0 +1,54 @@
+package protocol
+
+import (
+    "strconv"
+    "strings"
+)
+
+// Protocol versions. A client asks for one in initialize; a session that
+// never negotiates speaks CurrentVersion.
+const (
+    Version1 = "1.0"
+    // Version2 drops the adapter-only variable commands (getLocalVariables,
+    // getLocalVariable and getGlobalVariables); clients use scopes and
+    // variables instead. Breakpoint changes are reported with the breakpoint
+    // event rather than breakpointValidated.
+    Version2 = "2.0"
+
+    CurrentVersion = Version1
+)
+
+// Versions lists the supported versions, oldest first.
+var Versions = []string{Version1, Version2}
+
+// Supported reports whether v is one of Versions.
+func Supported(v string) bool {
+    for _, s := range Versions { if s == v { return true } }
+    return false
+}
+
+// CompareVersions orders two "major.minor" versions like strings.Compare.
+func CompareVersions(a, b string) int {
+    pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+    for i := 0; i < max(len(pa), len(pb)); i++ {
+        x, y := 0, 0
+        if i < len(pa) { x, _ = strconv.Atoi(pa[i]) }
+        if i < len(pb) { y, _ = strconv.Atoi(pb[i]) }
+        if x != y { if x < y { return -1 }; return 1 }
+    }
+    return 0
+}
+
+// Removed maps commands to the version that removed them.
+var Removed = map[string]string{
+    "getLocalVariables":  Version2,
+    "getLocalVariable":   Version2,
+    "getGlobalVariables": Version2,
+}
+
+// Available reports whether command exists in version v. Custom commands
+// are always available.
+func Available(command, v string) bool {
+    r, ok := Removed[command]
+    return !ok || CompareVersions(v, r) < 0
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+    "encoding/json"
+    "io"
+    "sync/atomic"
+
+    en "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/engine"
+    p "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/protocol"
+)
+
+// jsonDebugger turns engine callbacks into protocol events on w.
+type jsonDebugger struct {
+    w    io.Writer
+    enc  *json.Encoder
+    file string
+    // set for protocol 2.0 sessions, which get breakpoint events instead
+    // of breakpointValidated
+    bpEvents atomic.Bool
+}
+
+func newJSONDebugger(w io.Writer) *jsonDebugger { return &jsonDebugger{w: w, enc: json.NewEncoder(w)} }
+
+func (d *jsonDebugger) ev(name string, body any) {
+    _ = d.enc.Encode(p.Event{Type: "event", Event: name, Body: body})
+}
+func (d *jsonDebugger) OnStopOnEntry(line int, column *int)               { d.ev("stopped", map[string]any{"reason": "entry", "line": line, "column": n2i(column)}) }
+func (d *jsonDebugger) OnStopOnStep(line int, column *int)                { d.ev("stopped", map[string]any{"reason": "step", "line": line, "column": n2i(column)}) }
+func (d *jsonDebugger) OnStopOnBreakpoint(line int, column *int)          { d.ev("stopped", map[string]any{"reason": "breakpoint", "line": line, "column": n2i(column)}) }
+func (d *jsonDebugger) OnStopOnException(line int, ex *string, column *int) { d.ev("stopped", map[string]any{"reason": "exception", "exception": ex, "line": line, "column": n2i(column)}) }
+func (d *jsonDebugger) OnStopOnDataBreakpoint(line int, column *int)      { d.ev("stopped", map[string]any{"reason": "dataBreakpoint", "line": line, "column": n2i(column)}) }
+func (d *jsonDebugger) OnStopOnInstructionBreakpoint(line int, column *int) {
+    d.ev("stopped", map[string]any{"reason": "instructionBreakpoint", "line": line, "column": n2i(column)})
+}
+func (d *jsonDebugger) OnStopOnPause(line int, column *int)               { d.ev("stopped", map[string]any{"reason": "pause", "line": line, "column": n2i(column)}) }
+func (d *jsonDebugger) OnStopOnGoto(line int, column *int)                { d.ev("stopped", map[string]any{"reason": "goto", "line": line, "column": n2i(column)}) }
+func (d *jsonDebugger) OnBreakpoint(reason string, bp en.Breakpoint) {
+    if d.bpEvents.Load() { d.ev("breakpoint", map[string]any{"reason": reason, "breakpoint": bp.Body()}); return }
+    // 1.0 only reports breakpoints becoming verified or moving; new ones
+    // are in the setBreakpoints response
+    if reason != "changed" { return }
+    d.ev("breakpointValidated", map[string]any{"id": bp.ID, "line": bp.Line, "verified": bp.Verified})
+}
+func (d *jsonDebugger) OnOutput(o en.Output) { d.ev("output", o.Body()) }
+func (d *jsonDebugger) OnLoadedSource(reason string, source p.Source) {
+    d.ev("loadedSource", map[string]any{"reason": reason, "source": source})
+}
+func (d *jsonDebugger) OnModule(reason string, module p.Module) {
+    d.ev("module", map[string]any{"reason": reason, "module": module})
+}
+func (d *jsonDebugger) OnInvalidated(areas []string) { d.ev("invalidated", map[string]any{"areas": areas}) }
+func (d *jsonDebugger) OnProgressStart(id, title string, cancellable bool) {
+    d.ev("progressStart", map[string]any{"progressId": id, "title": title, "cancellable": cancellable})
+}
+func (d *jsonDebugger) OnProgressUpdate(id string, percentage int, message string) {
+    d.ev("progressUpdate", map[string]any{"progressId": id, "percentage": percentage, "message": message})
+}
+func (d *jsonDebugger) OnProgressEnd(id, message string) {
+    body := map[string]any{"progressId": id}
+    if message != "" { body["message"] = message }
+    d.ev("progressEnd", body)
+}
+func (d *jsonDebugger) OnEnd() { d.ev("terminated", map[string]any{}) }
+func (d *jsonDebugger) OnMemory(ref string, offset, count int) {
+    d.ev("memory", map[string]any{"memoryReference": ref, "offset": offset, "count": count})
+}
+
+func n2i(p *int) any { if p == nil { return nil }; return *p }
@@ -0,0 +1,4 @@
+// Package server speaks the mock runtime protocol for an engine: ServeConn
+// runs one session over any reader/writer pair (stdio, pipes, sockets) and
+// ListenAndServe serves a session per TCP connection.
+package server
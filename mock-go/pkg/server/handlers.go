@@ -0,0 +1,304 @@
+package server
+
+import (
+    "encoding/base64"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+
+    en "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/engine"
+    p "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/protocol"
+)
+
+// registerBuiltins registers the commands of PROTOCOL.md.
+func (s *Server) registerBuiltins() {
+    for command, h := range map[string]Handler{
+        "initialize":                  Typed(initialize),
+        "attach":                      Typed(attach),
+        "launch":                      Typed(launch),
+        "input":                       Typed(input),
+        "setBreakpoints":              Typed(setBreakpoints),
+        "source":                      Typed(source),
+        "loadedSources":               Typed(loadedSources),
+        "modules":                     Typed(modules),
+        "continue":                    Typed(continueRequest),
+        "disconnect":                  Typed(disconnect),
+        "pause":                       Typed(pause),
+        "cancel":                      Typed(cancel),
+        "gotoTargets":                 Typed(gotoTargets),
+        "goto":                        Typed(gotoRequest),
+        "next":                        Typed(next),
+        "stepIn":                      Typed(stepIn),
+        "stepOut":                     Typed(stepOut),
+        "stackTrace":                  Typed(stackTrace),
+        "breakpointLocations":         Typed(breakpointLocations),
+        "breakpointLines":             Typed(breakpointLines),
+        "disassemble":                 Typed(disassemble),
+        "getLocalVariables":           Typed(getLocalVariables),
+        "getLocalVariable":            Typed(getLocalVariable),
+        "setVariable":                 Typed(setVariable),
+        "scopes":                      Typed(scopes),
+        "variables":                   Typed(variables),
+        "setExpression":               Typed(setExpression),
+        "evaluate":                    Typed(evaluate),
+        "completions":                 Typed(completions),
+        "readMemory":                  Typed(readMemory),
+        "writeMemory":                 Typed(writeMemory),
+        "getGlobalVariables":          Typed(getGlobalVariables),
+        "setExceptionBreakpoints":     Typed(setExceptionBreakpoints),
+        "setDataBreakpoint":           Typed(setDataBreakpoint),
+        "clearAllDataBreakpoints":     Typed(clearAllDataBreakpoints),
+        "setInstructionBreakpoint":    Typed(setInstructionBreakpoint),
+        "clearInstructionBreakpoints": Typed(clearInstructionBreakpoints),
+    } { s.Handle(command, h) }
+}
+
+func initialize(s *Session, a p.InitializeArgs) (any, error) {
+    v := a.ProtocolVersion
+    if v == "" { v = p.CurrentVersion }
+    if !p.Supported(v) {
+        return nil, fmt.Errorf("unsupported protocol version %s; supported versions: %s", v, strings.Join(p.Versions, ", "))
+    }
+    s.version = v
+    s.dbg.bpEvents.Store(p.CompareVersions(v, p.Version2) >= 0)
+    return p.InitializeBody{ProtocolVersion: v, SupportedVersions: p.Versions}, nil
+}
+
+func attach(s *Session, a p.AttachArgs) (any, error) {
+    eng := s.Engine
+    if a.StopOnAttach { s.After(eng.Pause) }
+    return p.AttachBody{Program: eng.SourceFile(), SourceLength: eng.SourceLength()}, nil
+}
+
+func launch(s *Session, a p.LaunchArgs) (any, error) {
+    eng := s.Engine
+    cfg := en.LaunchConfig{Args: a.Args, Env: map[string]string{}, Cwd: a.Cwd, NoDebug: a.NoDebug}
+    // DAP allows null values to unset a variable; skip those
+    for name, v := range a.Env { if v != nil { cfg.Env[name] = *v } }
+    program := a.Program
+    var data []byte
+    if a.ProgramText == nil {
+        if cfg.Cwd != "" && !filepath.IsAbs(program) { program = filepath.Join(cfg.Cwd, program) }
+        var err error
+        data, err = os.ReadFile(program)
+        if err != nil { return nil, errors.New("cannot read program") }
+    }
+    // the previous program must neither keep running nor read the new
+    // program's input
+    s.stopInput()
+    s.stopInput = func() {}
+    eng.Stop()
+    eng.ResetInput()
+    if a.RelocateBreakpoints != nil { eng.SetRelocateBreakpoints(*a.RelocateBreakpoints) }
+    eng.Configure(cfg)
+    if a.ProgramText != nil {
+        // in-memory program: frames and breakpoints use its sourceReference
+        eng.LoadInline(a.ProgramName, []byte(*a.ProgramText))
+    } else {
+        eng.LoadSource(program, data)
+    }
+    s.stopWatch()
+    s.stopWatch = func() {}
+    if a.Watch { s.watch() }
+    if a.Stdin != "" {
+        f, err := os.Open(eng.ResolvePath(a.Stdin))
+        if err != nil { return nil, errors.New("cannot open stdin: " + a.Stdin) }
+        s.stopInput = feedInput(eng, f)
+    }
+    s.After(func() { if a.StopOnEntry && !a.NoDebug { s.dbg.OnStopOnEntry(0, nil) } else { eng.Go(func() { eng.Continue(false) }) } })
+    if a.ProgramText != nil { return p.LaunchBody{SourceReference: eng.SourceRef()}, nil }
+    return nil, nil
+}
+
+func input(s *Session, a p.InputArgs) (any, error) {
+    s.Engine.Input(a.Text)
+    return nil, nil
+}
+
+func setBreakpoints(s *Session, a p.SetBreakpointsArgs) (any, error) {
+    return p.SetBreakpointsBody{Breakpoints: s.Engine.SetBreakpoints(a.Path, a.SourceReference, a.Lines)}, nil
+}
+
+func source(s *Session, a p.SourceArgs) (any, error) {
+    content, ok := s.Engine.SourceContent(a.SourceReference)
+    if !ok { return nil, errors.New("unknown sourceReference") }
+    return p.SourceBody{Content: content, MimeType: "text/markdown"}, nil
+}
+
+func loadedSources(s *Session, a p.LoadedSourcesArgs) (any, error) {
+    return p.LoadedSourcesBody{Sources: s.Engine.LoadedSources()}, nil
+}
+
+func modules(s *Session, a p.ModulesArgs) (any, error) {
+    mods, total := s.Engine.Modules(a.StartModule, a.ModuleCount)
+    return p.ModulesBody{Modules: mods, TotalModules: total}, nil
+}
+
+func continueRequest(s *Session, a p.ContinueArgs) (any, error) {
+    s.After(func() { s.Engine.Go(func() { s.Engine.Continue(a.Reverse) }) })
+    return nil, nil
+}
+
+func disconnect(s *Session, a p.DisconnectArgs) (any, error) {
+    // a program left running would keep its goroutine busy after the client is gone
+    s.After(func() { s.stopInput(); s.Engine.CloseInput(); s.Engine.Stop() })
+    s.Close()
+    return nil, nil
+}
+
+func pause(s *Session, a p.PauseArgs) (any, error) {
+    s.After(s.Engine.Pause)
+    return nil, nil
+}
+
+func cancel(s *Session, a p.CancelArgs) (any, error) {
+    // only progress can be cancelled; requests are answered synchronously
+    s.After(func() { s.Engine.Cancel(a.ProgressID) })
+    return nil, nil
+}
+
+func gotoTargets(s *Session, a p.GotoTargetsArgs) (any, error) {
+    return p.GotoTargetsBody{Targets: s.Engine.GotoTargets(a.Line)}, nil
+}
+
+func gotoRequest(s *Session, a p.GotoArgs) (any, error) {
+    if err := s.Engine.Goto(a.TargetID); err != nil { return nil, err }
+    s.After(s.Engine.NotifyGoto)
+    return nil, nil
+}
+
+func next(s *Session, a p.NextArgs) (any, error) {
+    // stepping over read() may block until an input request arrives; the
+    // step only finishes in the background then
+    s.After(func() { <-s.Engine.Go(func() { s.Engine.Next(a.Reverse) }) })
+    return nil, nil
+}
+
+func stepIn(s *Session, a p.StepInArgs) (any, error) {
+    s.After(func() { s.Engine.StepIn(a.TargetID) })
+    return nil, nil
+}
+
+func stepOut(s *Session, a p.StepOutArgs) (any, error) {
+    s.After(s.Engine.StepOut)
+    return nil, nil
+}
+
+func stackTrace(s *Session, a p.StackTraceArgs) (any, error) {
+    levels := 1000
+    if a.Levels != nil { levels = *a.Levels }
+    frames, count := s.Engine.BuildStack(a.StartFrame, a.StartFrame+levels)
+    return p.StackTraceBody{StackFrames: frames, TotalFrames: count}, nil
+}
+
+func breakpointLocations(s *Session, a p.BreakpointLocationsArgs) (any, error) {
+    cols := s.Engine.GetBreakpointColumns(a.Path, a.Line)
+    locs := make([]p.BreakpointLocation, 0, len(cols))
+    for _, c := range cols { locs = append(locs, p.BreakpointLocation{Column: c}) }
+    return p.BreakpointLocationsBody{Breakpoints: locs}, nil
+}
+
+func breakpointLines(s *Session, a p.BreakpointLinesArgs) (any, error) {
+    return p.BreakpointLinesBody{Lines: s.Engine.GetBreakpointLines()}, nil
+}
+
+func disassemble(s *Session, a p.DisassembleArgs) (any, error) {
+    count := 32
+    if a.InstructionCount != nil { count = *a.InstructionCount }
+    return p.DisassembleBody{Instructions: s.Engine.Disassemble(a.Address, count)}, nil
+}
+
+func getLocalVariables(s *Session, a p.GetLocalVariablesArgs) (any, error) {
+    return p.VariablesBody{Variables: s.Engine.GetLocalVariables()}, nil
+}
+
+func getLocalVariable(s *Session, a p.GetLocalVariableArgs) (any, error) {
+    return p.GetLocalVariableBody{Variable: s.Engine.GetLocalVariable(a.Name)}, nil
+}
+
+func setVariable(s *Session, a p.SetVariableArgs) (any, error) {
+    v, err := s.Engine.SetVariable(a.VariablesReference, a.Name, a.Value)
+    if err != nil { return nil, err }
+    s.After(func() { s.Engine.NotifyWrite(a.VariablesReference, a.Name) })
+    return stored(v), nil
+}
+
+func scopes(s *Session, a p.ScopesArgs) (any, error) {
+    return p.ScopesBody{Scopes: s.Engine.Scopes()}, nil
+}
+
+func variables(s *Session, a p.VariablesArgs) (any, error) {
+    vars, ok := s.Engine.Variables(a.VariablesReference)
+    if !ok { return nil, errors.New("unknown variablesReference") }
+    return p.VariablesBody{Variables: vars}, nil
+}
+
+func setExpression(s *Session, a p.SetExpressionArgs) (any, error) {
+    v, err := s.Engine.SetExpression(a.Expression, a.Value)
+    if err != nil { return nil, err }
+    s.After(func() { s.Engine.NotifyWrite(0, a.Expression) })
+    return stored(v), nil
+}
+
+func evaluate(s *Session, a p.EvaluateArgs) (any, error) {
+    v, err := s.Engine.Evaluate(a.Expression)
+    if err != nil { return nil, err }
+    return p.EvaluateBody{Value: v.Value, Type: v.Type, VariablesReference: v.VariablesReference}, nil
+}
+
+// stored answers a write with the value as it was stored.
+func stored(v p.Variable) p.SetVariableBody {
+    return p.SetVariableBody{Value: v.Value, Type: v.Type, VariablesReference: v.VariablesReference, MemoryReference: v.MemoryReference}
+}
+
+func completions(s *Session, a p.CompletionsArgs) (any, error) {
+    column := len(a.Text)
+    if a.Column != nil { column = *a.Column }
+    return p.CompletionsBody{Targets: s.Engine.Completions(a.Text, column)}, nil
+}
+
+func readMemory(s *Session, a p.ReadMemoryArgs) (any, error) {
+    addr, data, unreadable, err := s.Engine.ReadMemory(a.MemoryReference, a.Offset, a.Count)
+    if err != nil { return nil, err }
+    return p.ReadMemoryBody{Address: addr, Data: base64.StdEncoding.EncodeToString(data), UnreadableBytes: unreadable}, nil
+}
+
+func writeMemory(s *Session, a p.WriteMemoryArgs) (any, error) {
+    data, err := base64.StdEncoding.DecodeString(a.Data)
+    if err != nil { return nil, errors.New("data is not valid base64") }
+    n, err := s.Engine.WriteMemory(a.MemoryReference, a.Offset, data, a.AllowPartial)
+    if err != nil { return nil, err }
+    if n > 0 { s.After(func() { s.dbg.OnMemory(a.MemoryReference, a.Offset, n); s.dbg.OnInvalidated([]string{"variables"}) }) }
+    return p.WriteMemoryBody{BytesWritten: n}, nil
+}
+
+func getGlobalVariables(s *Session, a p.GetGlobalVariablesArgs) (any, error) {
+    return p.VariablesBody{Variables: s.Engine.GetGlobalVariables()}, nil
+}
+
+func setExceptionBreakpoints(s *Session, a p.SetExceptionBreakpointsArgs) (any, error) {
+    var named *string
+    if a.NamedException != "" { named = &a.NamedException }
+    s.Engine.SetExceptionsFilters(named, a.OtherExceptions)
+    return nil, nil
+}
+
+func setDataBreakpoint(s *Session, a p.SetDataBreakpointArgs) (any, error) {
+    return p.VerifiedBody{Verified: s.Engine.SetDataBreakpoint(a.Address, a.AccessType)}, nil
+}
+
+func clearAllDataBreakpoints(s *Session, a p.ClearAllDataBreakpointsArgs) (any, error) {
+    s.Engine.ClearAllDataBreakpoints()
+    return nil, nil
+}
+
+func setInstructionBreakpoint(s *Session, a p.SetInstructionBreakpointArgs) (any, error) {
+    return p.VerifiedBody{Verified: s.Engine.SetInstructionBreakpoint(a.Address)}, nil
+}
+
+func clearInstructionBreakpoints(s *Session, a p.ClearInstructionBreakpointsArgs) (any, error) {
+    s.Engine.ClearInstructionBreakpoints()
+    return nil, nil
+}
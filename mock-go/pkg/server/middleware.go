@@ -0,0 +1,35 @@
+package server
+
+import (
+    "log"
+    "time"
+
+    p "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/protocol"
+)
+
+// Logging logs every request with how long it took and, when it failed, why.
+func Logging(l *log.Logger) Middleware {
+    return func(next Handler) Handler {
+        return func(s *Session, req p.Request) (any, error) {
+            start := time.Now()
+            body, err := next(s, req)
+            if err != nil {
+                l.Printf("%s #%d failed after %v: %v", req.Command, req.ID, time.Since(start), err)
+            } else { l.Printf("%s #%d ok in %v", req.Command, req.ID, time.Since(start)) }
+            return body, err
+        }
+    }
+}
+
+// Metrics reports the command, duration and outcome of every request to
+// observe, e.g. to feed counters and histograms.
+func Metrics(observe func(command string, took time.Duration, err error)) Middleware {
+    return func(next Handler) Handler {
+        return func(s *Session, req p.Request) (any, error) {
+            start := time.Now()
+            body, err := next(s, req)
+            observe(req.Command, time.Since(start), err)
+            return body, err
+        }
+    }
+}
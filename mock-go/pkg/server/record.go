@@ -0,0 +1,181 @@
+package server
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "reflect"
+    "sync"
+    "time"
+)
+
+// A recording is a file of JSON lines, one Entry each: the session's
+// options first, then every line read from the client and every message
+// written to it, in the order they happened.
+
+// Entry is one line of a recording. Dir is "options", "in" or "out"; Msg
+// holds the message, or Text a client line that was not JSON.
+type Entry struct {
+    T    float64         `json:"t"` // milliseconds since the session started
+    Dir  string          `json:"dir"`
+    Msg  json.RawMessage `json:"msg,omitempty"`
+    Text string          `json:"text,omitempty"`
+}
+
+// line is the entry's message as it went over the wire.
+func (e Entry) line() string {
+    if e.Msg != nil { return string(e.Msg) }
+    return e.Text
+}
+
+// recorder appends entries to a recording. Events are written from the
+// engine's goroutines, so it locks.
+type recorder struct {
+    mu    sync.Mutex
+    enc   *json.Encoder
+    start time.Time
+}
+
+func newRecorder(w io.Writer, opts Options) *recorder {
+    r := &recorder{enc: json.NewEncoder(w), start: time.Now()}
+    data, _ := json.Marshal(opts)
+    r.add("options", data)
+    return r
+}
+
+func (r *recorder) add(dir string, line []byte) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.entry(dir, line)
+}
+
+// entry writes an entry; the caller holds mu.
+func (r *recorder) entry(dir string, line []byte) {
+    e := Entry{T: float64(time.Since(r.start).Microseconds()) / 1000, Dir: dir}
+    if line = bytes.TrimSpace(line); json.Valid(line) { e.Msg = append(json.RawMessage(nil), line...) } else { e.Text = string(line) }
+    _ = r.enc.Encode(e)
+}
+
+// recordingWriter records every message written through it; each message
+// is one Write, and the lock keeps the recording in the order of the
+// stream.
+type recordingWriter struct {
+    w   io.Writer
+    rec *recorder
+}
+
+func (w recordingWriter) Write(b []byte) (int, error) {
+    w.rec.mu.Lock()
+    defer w.rec.mu.Unlock()
+    w.rec.entry("out", b)
+    return w.w.Write(b)
+}
+
+// Recording is a recorded session.
+type Recording struct {
+    Options Options
+    Entries []Entry
+}
+
+// ReadRecording parses a recording written by a session with Options.Record.
+func ReadRecording(r io.Reader) (Recording, error) {
+    var rec Recording
+    sc := bufio.NewScanner(r)
+    sc.Buffer(make([]byte, 0, 1024*1024), 16*1024*1024)
+    for n := 1; sc.Scan(); n++ {
+        if len(bytes.TrimSpace(sc.Bytes())) == 0 { continue }
+        var e Entry
+        if err := json.Unmarshal(sc.Bytes(), &e); err != nil { return rec, fmt.Errorf("recording line %d: %v", n, err) }
+        switch e.Dir {
+        case "options":
+            if err := json.Unmarshal(e.Msg, &rec.Options); err != nil { return rec, fmt.Errorf("recording line %d: %v", n, err) }
+        case "in", "out":
+            rec.Entries = append(rec.Entries, e)
+        default:
+            return rec, fmt.Errorf("recording line %d: unknown direction %q", n, e.Dir)
+        }
+    }
+    return rec, sc.Err()
+}
+
+// Mismatch is where a replay wrote something else than the recording: the
+// messages that followed the client line Request ("" before the first one).
+type Mismatch struct {
+    Request  string
+    Expected []string
+    Got      []string
+}
+
+// replayTimeout is how long a replay waits for the next expected message.
+const replayTimeout = 2 * time.Second
+
+// Replay runs a new session, feeds it the client lines of rec and compares
+// what it writes with the recorded messages. Before sending a line it waits
+// until the messages that preceded it in the recording have arrived, so
+// events of a running program land where they did when recording. Build
+// the server from rec.Options to reproduce the recorded session.
+func (s *Server) Replay(rec Recording) []Mismatch {
+    type segment struct {
+        request string
+        want    []string
+    }
+    segs := []segment{{}}
+    for _, e := range rec.Entries {
+        if e.Dir == "in" { segs = append(segs, segment{request: e.line()}); continue }
+        segs[len(segs)-1].want = append(segs[len(segs)-1].want, e.line())
+    }
+
+    inR, inW := io.Pipe()
+    outR, outW := io.Pipe()
+    go func() {
+        s.ServeConn(inR, outW)
+        outW.Close()
+    }()
+    defer inW.Close()
+    lines := make(chan string, 1024)
+    go func() {
+        defer close(lines)
+        sc := bufio.NewScanner(outR)
+        sc.Buffer(make([]byte, 0, 1024*1024), 16*1024*1024)
+        for sc.Scan() { if l := string(bytes.TrimSpace(sc.Bytes())); l != "" { lines <- l } }
+    }()
+
+    var out []Mismatch
+    for i, seg := range segs {
+        if i > 0 { _, _ = io.WriteString(inW, seg.request+"\n") }
+        got := []string{}
+        // after the last line, also wait a moment for messages the recording lacks
+        last := i == len(segs)-1
+    collect:
+        for len(got) < len(seg.want) || last {
+            wait := replayTimeout
+            if len(got) >= len(seg.want) { wait = 100 * time.Millisecond }
+            select {
+            case l, ok := <-lines:
+                if !ok { break collect }
+                got = append(got, l)
+            case <-time.After(wait):
+                break collect
+            }
+        }
+        if !sameMessages(seg.want, got) { out = append(out, Mismatch{Request: seg.request, Expected: seg.want, Got: got}) }
+    }
+    go func() { for range lines {} }()
+    return out
+}
+
+func sameMessages(a, b []string) bool {
+    if len(a) != len(b) { return false }
+    for i := range a { if !SameMessage(a[i], b[i]) { return false } }
+    return true
+}
+
+// SameMessage compares two recorded lines as JSON values, ignoring key
+// order; lines that are not JSON must match exactly.
+func SameMessage(a, b string) bool {
+    var x, y any
+    if json.Unmarshal([]byte(a), &x) != nil || json.Unmarshal([]byte(b), &y) != nil { return a == b }
+    return reflect.DeepEqual(x, y)
+}
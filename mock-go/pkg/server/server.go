@@ -0,0 +1,297 @@
+package server
+
+import (
+    "bufio"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "net"
+    "os"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    en "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/engine"
+    p "github.com/Akeit0/vscode-mock-debug-custom-runtimes/mock-go/pkg/protocol"
+)
+
+// watchInterval is how often a watched program file is polled for changes.
+const watchInterval = 500 * time.Millisecond
+
+// Options configure a session. Program is preloaded and run at once (or
+// stopped on entry); Engine options apply to the session's engine. Record,
+// when set, is called as each session starts for the writer its recording
+// goes to (nil to not record); the session closes it if it is an io.Closer.
+type Options struct {
+    Program             string           `json:"program,omitempty"`
+    StopOnEntry         bool             `json:"stopOnEntry,omitempty"`
+    Watch               bool             `json:"watch,omitempty"`
+    RelocateBreakpoints bool             `json:"relocateBreakpoints,omitempty"`
+    Engine              []en.Option      `json:"-"`
+    Record              func() io.Writer `json:"-"`
+}
+
+// Handler answers one request. A nil error sends a success response with
+// body (none when body is nil); an error sends a failure with its message.
+type Handler func(s *Session, req p.Request) (body any, err error)
+
+// Typed adapts a handler that takes the command's arguments decoded into
+// A, one of the protocol *Args structs or any struct of the same kind.
+// Malformed arguments fail the request before h runs.
+func Typed[A any](h func(s *Session, args A) (any, error)) Handler {
+    return func(s *Session, req p.Request) (any, error) {
+        var args A
+        if err := req.Decode(&args); err != nil { return nil, err }
+        return h(s, args)
+    }
+}
+
+// Middleware wraps every handler, built-in or custom, including the one
+// answering unknown commands. Use it for logging, auth or metrics.
+type Middleware func(next Handler) Handler
+
+// Server dispatches requests to the handler registered for their command.
+// A new Server knows every command of PROTOCOL.md; Handle adds or replaces
+// commands and Use adds middleware. Configure it before serving.
+type Server struct {
+    opts       Options
+    handlers   map[string]Handler
+    middleware []Middleware
+}
+
+// New returns a Server with the built-in commands registered.
+func New(opts Options) *Server {
+    s := &Server{opts: opts, handlers: map[string]Handler{}}
+    s.registerBuiltins()
+    return s
+}
+
+// Handle registers h for command, replacing any earlier handler.
+func (s *Server) Handle(command string, h Handler) { s.handlers[command] = h }
+
+// Use appends middleware; the first one added is the outermost.
+func (s *Server) Use(m ...Middleware) { s.middleware = append(s.middleware, m...) }
+
+// Commands lists the registered commands in alphabetical order.
+func (s *Server) Commands() []string {
+    out := make([]string, 0, len(s.handlers))
+    for c := range s.handlers { out = append(out, c) }
+    sort.Strings(out)
+    return out
+}
+
+func (s *Server) handler(command string) Handler {
+    h, ok := s.handlers[command]
+    if !ok { h = unknownCommand }
+    h = versioned(h)
+    for i := len(s.middleware) - 1; i >= 0; i-- { h = s.middleware[i](h) }
+    return h
+}
+
+func unknownCommand(_ *Session, req p.Request) (any, error) {
+    return nil, errors.New("unknown command: " + req.Command)
+}
+
+// versioned fails commands that the session's protocol version removed.
+func versioned(next Handler) Handler {
+    return func(s *Session, req p.Request) (any, error) {
+        if !p.Available(req.Command, s.version) {
+            return nil, fmt.Errorf("%s was removed in protocol %s (session speaks %s)", req.Command, p.Removed[req.Command], s.version)
+        }
+        return next(s, req)
+    }
+}
+
+// Session is one connection: the engine it debugs and the stream its
+// responses and events go to.
+type Session struct {
+    Engine *en.Engine
+    // Values holds per-session state of middleware and custom handlers.
+    Values map[string]any
+
+    version   string
+    dbg       *jsonDebugger
+    enc       *json.Encoder
+    stopWatch func()
+    stopInput func()
+    after     []func()
+    closed    bool
+
+    // program changes from the watcher, applied between requests
+    reloads chan reload
+    pending *reload
+}
+
+// reload is a new version of the watched program.
+type reload struct {
+    path     string
+    contents []byte
+}
+
+// watch starts watching the loaded program, replacing any earlier watcher.
+func (s *Session) watch() {
+    s.stopWatch()
+    s.stopWatch = s.Engine.Watch(watchInterval, func(path string, contents []byte) {
+        // keep only the latest version; the watcher is the only sender
+        select {
+        case <-s.reloads:
+        default:
+        }
+        s.reloads <- reload{path, contents}
+    })
+}
+
+// applyReload reloads the program if a change is pending and the program is
+// not running; otherwise the change stays pending.
+func (s *Session) applyReload() {
+    if s.pending != nil && s.Engine.Reload(s.pending.path, s.pending.contents) { s.pending = nil }
+}
+
+// Version is the protocol version the session speaks.
+func (s *Session) Version() string { return s.version }
+
+// Event sends an event to the client.
+func (s *Session) Event(name string, body any) { s.dbg.ev(name, body) }
+
+// After runs f once the response to the current request is written. Events
+// describing the effect of a request go there so they follow its response.
+func (s *Session) After(f func()) { s.after = append(s.after, f) }
+
+// Close ends the session after the current request.
+func (s *Session) Close() { s.closed = true }
+
+// ListenAndServe accepts TCP connections on addr and serves each as its own
+// session. It only returns when listening fails.
+func ListenAndServe(addr string, opts Options) error { return New(opts).ListenAndServe(addr) }
+
+// ServeConn runs one debug session, reading requests from r and writing
+// responses and events to w until r ends or the client disconnects.
+func ServeConn(r io.Reader, w io.Writer, opts Options) { New(opts).ServeConn(r, w) }
+
+// ListenAndServe accepts TCP connections on addr and serves each as its own
+// session. It only returns when listening fails.
+func (s *Server) ListenAndServe(addr string) error {
+    ln, err := net.Listen("tcp", addr)
+    if err != nil { return err }
+    log.Printf("Listening on %s...", addr)
+    for {
+        conn, err := ln.Accept()
+        if err != nil { log.Printf("accept: %v", err); continue }
+        log.Printf("Client connected")
+        go func(c net.Conn) {
+            defer c.Close()
+            s.ServeConn(c, c)
+            log.Printf("Client disconnected")
+        }(conn)
+    }
+}
+
+// ServeConn runs one debug session, reading requests from r and writing
+// responses and events to w until r ends or the client disconnects.
+func (s *Server) ServeConn(r io.Reader, w io.Writer) {
+    opts := s.opts
+    var rec *recorder
+    if opts.Record != nil {
+        if rw := opts.Record(); rw != nil {
+            rec = newRecorder(rw, opts)
+            w = recordingWriter{w: w, rec: rec}
+            if c, ok := rw.(io.Closer); ok { defer c.Close() }
+        }
+    }
+    dbg := newJSONDebugger(w)
+    eng := en.New(dbg, append([]en.Option{en.WithRelocateBreakpoints(opts.RelocateBreakpoints)}, opts.Engine...)...)
+    sess := &Session{Engine: eng, Values: map[string]any{}, version: p.CurrentVersion, dbg: dbg, enc: json.NewEncoder(w),
+        stopWatch: func() {}, stopInput: func() {}, reloads: make(chan reload, 1)}
+    defer func() { sess.stopWatch(); sess.stopInput(); eng.Stop() }()
+
+    if opts.Program != "" {
+        data, err := os.ReadFile(opts.Program)
+        if err == nil {
+            eng.LoadSource(opts.Program, data)
+            if opts.Watch { sess.watch() }
+            if opts.StopOnEntry { dbg.OnStopOnEntry(0, nil) } else { eng.Go(func() { eng.Continue(false) }) }
+        }
+    }
+
+    // requests and program changes are handled one at a time on this goroutine
+    lines, done := make(chan string), make(chan struct{})
+    defer close(done)
+    go func() {
+        defer close(lines)
+        scanner := bufio.NewScanner(r)
+        buf := make([]byte, 0, 1024*1024)
+        scanner.Buffer(buf, 1024*1024)
+        for scanner.Scan() {
+            select {
+            case lines <- scanner.Text():
+            case <-done: return
+            }
+        }
+    }()
+    for {
+        // a change that arrived during a run is retried until the run settles
+        var retry <-chan time.Time
+        if sess.pending != nil { retry = time.After(watchInterval) }
+        var line string
+        select {
+        case l, ok := <-lines:
+            if !ok { return }
+            line = l
+        case r := <-sess.reloads:
+            sess.pending = &r
+        case <-retry:
+        }
+        sess.applyReload()
+        if strings.TrimSpace(line) == "" { continue }
+        if rec != nil { rec.add("in", []byte(line)) }
+        var req p.Request
+        if err := json.Unmarshal([]byte(line), &req); err != nil { _ = sess.enc.Encode(p.Fail(-1, "invalid json")); continue }
+        if !strings.EqualFold(req.Type, "request") { continue }
+
+        sess.after = nil
+        body, err := s.handler(req.Command)(sess, req)
+        switch {
+        case err != nil:
+            _ = sess.enc.Encode(p.Fail(req.ID, err.Error()))
+        case body == nil:
+            _ = sess.enc.Encode(p.OkEmpty(req.ID))
+        default:
+            _ = sess.enc.Encode(p.Ok(req.ID, body))
+        }
+        // a failed request has no effect to report
+        if err == nil { for _, f := range sess.after { f() } }
+        if sess.closed { return }
+    }
+}
+
+// feedInput streams a launch-time stdin file into the engine line by line
+// until the file ends or the returned stop function is called. stop waits
+// for the feeder to exit, so no line reaches the next program.
+func feedInput(eng *en.Engine, f *os.File) (stop func()) {
+    done, exited := make(chan struct{}), make(chan struct{})
+    go func() {
+        defer close(exited)
+        sc := bufio.NewScanner(f)
+        for sc.Scan() {
+            select {
+            case <-done: return
+            default: eng.Input(sc.Text())
+            }
+        }
+        select {
+        case <-done:
+        default: eng.CloseInput()
+        }
+    }()
+    var once sync.Once
+    return func() {
+        once.Do(func() {
+            close(done)
+            f.Close() // unblocks a pending read
+            <-exited
+        })
+    }
+}
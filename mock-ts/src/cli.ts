@@ -6,6 +6,8 @@ type Request = { type: 'request'; id: number; command: string; args?: Record<str
 type Response = { type: 'response'; id: number; success: boolean; body?: any; message?: string };
 type EventMsg = { type: 'event'; event: string; body?: any };
 
+const PROTOCOL_VERSIONS = ['1.0'];
+
 function ok(id: number, body?: any): Response { return { type: 'response', id, success: true, body }; }
 function fail(id: number, message: string): Response { return { type: 'response', id, success: false, message }; }
 
@@ -44,7 +46,12 @@ async function handleConnection(input: NodeJS.ReadableStream, output: NodeJS.Wri
 
       switch (req.command) {
         case 'initialize': {
-          writeJson(output, ok(req.id, { capabilities: {} }));
+          const version = req.args?.protocolVersion ?? PROTOCOL_VERSIONS[0];
+          if (!PROTOCOL_VERSIONS.includes(version)) {
+            writeJson(output, fail(req.id, `unsupported protocol version ${version}; supported versions: ${PROTOCOL_VERSIONS.join(', ')}`));
+            break;
+          }
+          writeJson(output, ok(req.id, { protocolVersion: version, supportedVersions: PROTOCOL_VERSIONS, capabilities: {} }));
           break;
         }
         case 'attach': {
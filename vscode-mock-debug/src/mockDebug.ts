@@ -123,7 +123,7 @@ export class MockDebugSession extends LoggingDebugSession {
 		this._runtime.on('breakpointValidated', (bp: IRuntimeBreakpoint) => {
 			this.sendEvent(new BreakpointEvent('changed', { verified: bp.verified, id: bp.id } as DebugProtocol.Breakpoint));
 		});
-		this._runtime.on('output', (type, text, filePath, line, column) => {
+		this._runtime.on('output', (type, text, filePath, line, column, group?: string, variable?: RuntimeVariable) => {
 
 			let category: string;
 			switch (type) {
@@ -134,10 +134,16 @@ export class MockDebugSession extends LoggingDebugSession {
 			}
 			const e: DebugProtocol.OutputEvent = new OutputEvent(`${text}\n`, category);
 
-			if (text === 'start' || text === 'startCollapsed' || text === 'end') {
+			if (group === 'start' || group === 'startCollapsed' || group === 'end') {
+				// external runtimes: the text is the group's name
+				e.body.group = group;
+			} else if (text === 'start' || text === 'startCollapsed' || text === 'end') {
 				e.body.group = text;
 				e.body.output = `group-${text}\n`;
 			}
+			if (variable) {
+				e.body.variablesReference = this._variableHandles.create(variable);
+			}
 
 			e.body.source = this.createSource(filePath);
 			e.body.line = this.convertDebuggerLineToClient(line);
@@ -172,7 +178,7 @@ export class MockDebugSession extends LoggingDebugSession {
 		this._runtime.on('breakpointValidated', (bp: IRuntimeBreakpoint) => {
 			this.sendEvent(new BreakpointEvent('changed', { verified: bp.verified, id: bp.id } as DebugProtocol.Breakpoint));
 		});
-		this._runtime.on('output', (type, text, filePath, line, column) => {
+		this._runtime.on('output', (type, text, filePath, line, column, group?: string, variable?: RuntimeVariable) => {
 			let category: string;
 			switch (type) {
 				case 'prio': category = 'important'; break;
@@ -181,10 +187,16 @@ export class MockDebugSession extends LoggingDebugSession {
 				default: category = 'console'; break;
 			}
 			const e: DebugProtocol.OutputEvent = new OutputEvent(`${text}\n`, category);
-			if (text === 'start' || text === 'startCollapsed' || text === 'end') {
+			if (group === 'start' || group === 'startCollapsed' || group === 'end') {
+				// external runtimes: the text is the group's name
+				e.body.group = group;
+			} else if (text === 'start' || text === 'startCollapsed' || text === 'end') {
 				e.body.group = text;
 				e.body.output = `group-${text}\n`;
 			}
+			if (variable) {
+				e.body.variablesReference = this._variableHandles.create(variable);
+			}
 			e.body.source = this.createSource(filePath);
 			e.body.line = this.convertDebuggerLineToClient(line);
 			e.body.column = this.convertDebuggerColumnToClient(column);
@@ -9,6 +9,8 @@ export abstract class RuntimeBase extends EventEmitter implements IRuntime {
   // transport/request plumbing
   private reqId = 1;
   private pending = new Map<number, { resolve: (v: any) => void; reject: (e: any) => void }>();
+  // events are handled one at a time, in arrival order, even when handling awaits a request
+  private eventQueue: Promise<void> = Promise.resolve();
 
   // source & caret state
   public sourceFile: string = '';
@@ -44,9 +46,20 @@ export abstract class RuntimeBase extends EventEmitter implements IRuntime {
       const p = this.pending.get(msg.id);
       if (p) { this.pending.delete(msg.id); msg.success ? p.resolve(msg.body ?? {}) : p.reject(new Error(msg.message || 'error')); }
     } else if (msg.type === 'event') {
-      void this.dispatchEvent(msg.event, msg.body || {});
+      this.eventQueue = this.eventQueue.then(() => this.dispatchEvent(msg.event, msg.body || {})).catch(() => { });
     }
   }
+  // Protocol version this adapter speaks; it relies on the 1.0 variable commands.
+  protected static readonly PROTOCOL_VERSION = '1.0';
+
+  // Runtimes that predate version negotiation ignore the argument and answer without protocolVersion.
+  protected async initialize(): Promise<void> {
+    const body = await this.send('initialize', { protocolVersion: RuntimeBase.PROTOCOL_VERSION });
+    if (body.protocolVersion && body.protocolVersion !== RuntimeBase.PROTOCOL_VERSION) {
+      throw new Error(`runtime speaks protocol ${body.protocolVersion}, expected ${RuntimeBase.PROTOCOL_VERSION}`);
+    }
+  }
+
   protected send(command: string, args?: any): Promise<any> {
     const id = this.reqId++;
     const payload = { type: 'request', id, command, args };
@@ -102,21 +115,38 @@ export abstract class RuntimeBase extends EventEmitter implements IRuntime {
         this.emit('breakpointValidated', { id: Number(id), verified: !!verified });
         break;
       }
+      case 'breakpoint': {
+        // full breakpoint events (mock-go); removals need no UI update here
+        const { reason, breakpoint } = body || {};
+        if (breakpoint && reason !== 'removed') { this.emit('breakpointValidated', { id: Number(breakpoint.id), verified: !!breakpoint.verified }); }
+        break;
+      }
       case 'variablesChanged': {
         void this.send('getLocalVariables').then((b) => { this.updateLocalsFromPayload(b); this.localsFresh = true; }).catch(() => { });
         this.emit('variablesChanged');
         break;
       }
       case 'output': {
-        const { category = 'stdout', text = '', file = this.sourceFile, line = 0, column = 1 } = body;
+        const { category = 'stdout', text = '', file = this.sourceFile, line = 0, column = 1, group, variablesReference } = body;
         this.lastOutputLine = Number(line) || 0;
         const cat = String(category).toLowerCase();
+        // telemetry is meant for the adapter, not the debug console
+        if (cat === 'telemetry') { break; }
         const kind = (cat === 'stderr' || cat === 'err') ? 'err'
           : (cat === 'stdout' || cat === 'out') ? 'out'
           : (cat === 'prio' || cat === 'important') ? 'prio'
           : 'log';
         if (!this.sourceFile && file) { this.sourceFile = String(file); }
-        this.emit('output', kind, String(text), String(file), Number(line), Number(column));
+        // a printed structured value (mock-go) is fetched so the session can expand it
+        let variable: RuntimeVariable | undefined;
+        if (Number(variablesReference) > 0) {
+          try {
+            const b = await this.send('variables', { variablesReference: Number(variablesReference) });
+            const arr = (b.variables as any[] | undefined) || [];
+            variable = new RuntimeVariable(String(text), arr.map((item: any) => new RuntimeVariable(String(item.name), this.toTsValue(item.value))));
+          } catch { /* plain text then */ }
+        }
+        this.emit('output', kind, String(text), String(file), Number(line), Number(column), group ? String(group) : undefined, variable);
         break;
       }
       case 'terminated': this.emit('end'); break;
@@ -156,7 +186,7 @@ export abstract class RuntimeBase extends EventEmitter implements IRuntime {
       const line = this.sourceLines[l]; let m: RegExpExecArray | null;
       while ((m = WORD_REGEXP.exec(line))) { this.instructions.push({ address: this.instructions.length, instruction: m[0], line: l }); }
     }
-    await this.send('initialize');
+    await this.initialize();
     await this.send('launch', { program, stopOnEntry: !!stopOnEntry });
     // initial locals refresh (best effort)
     void this.send('getLocalVariables').then((body) => { this.updateLocalsFromPayload(body); this.localsFresh = true; }).catch(() => { });
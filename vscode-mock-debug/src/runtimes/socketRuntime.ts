@@ -30,7 +30,7 @@ export class SocketRuntimeProxy extends RuntimeBase implements IRuntime {
     this.socket.on('data', this.onData);
     this.socket.on('close', () => this.emit('end'));
     this.sourceLines = [];
-    await this.send('initialize');
+    await this.initialize();
     const args: any = { stopOnAttach: !!stopOnAttach };
     const b = await this.send('attach', args);
     const program: string = b.program;